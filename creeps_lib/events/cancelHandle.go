@@ -5,6 +5,8 @@ import "sync/atomic"
 // zero value is a valid not-cancelled handle
 type CancelHandle struct {
 	cancelled atomic.Bool
+	// events not sent to the subscriber because its channel was full
+	dropped atomic.Uint64
 }
 
 func (h *CancelHandle) Cancel() {
@@ -14,3 +16,14 @@ func (h *CancelHandle) Cancel() {
 func (h *CancelHandle) IsCancelled() bool {
 	return h.cancelled.Load()
 }
+
+// called by providers that drop events instead of blocking
+func (h *CancelHandle) AddDropped() {
+	h.dropped.Add(1)
+}
+
+// returns the amount of events the subscriber missed because its channel was
+// full
+func (h *CancelHandle) GetDroppedCount() uint64 {
+	return h.dropped.Load()
+}
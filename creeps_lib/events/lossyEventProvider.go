@@ -0,0 +1,76 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// like EventProvider but safe to emit from multiple goroutines and never
+// blocks, events are dropped for subscribers whose channel is full
+// zero value is valid
+type LossyEventProvider[T any] struct {
+	mutex sync.Mutex
+	subs  []sub[T]
+
+	// if set, applied to every event before it is sent (ex: to add the tick
+	// at which it was emitted), must be set before anything is emitted
+	Stamp func(event T) T
+
+	// events not sent because the channel of the subscriber was full
+	dropped atomic.Uint64
+}
+
+func (provider *LossyEventProvider[T]) Subscribe(channel chan T) *CancelHandle {
+	handle := new(CancelHandle)
+	provider.SubscribeWithHandle(channel, handle)
+	return handle
+}
+
+func (provider *LossyEventProvider[T]) SubscribeWithHandle(channel chan T, handle *CancelHandle) {
+	if handle.IsCancelled() {
+		return
+	}
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.subs = append(provider.subs, sub[T]{
+		sendChan: channel,
+		handle:   handle,
+	})
+}
+
+func (provider *LossyEventProvider[T]) Emit(event T) {
+	if provider.Stamp != nil {
+		event = provider.Stamp(event)
+	}
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	var i int = 0
+	for i < len(provider.subs) {
+		sub := &provider.subs[i]
+		if sub.handle.IsCancelled() {
+			// not closed as unlike EventProvider the subscriber may still
+			// receive events from another goroutine
+			copy(provider.subs[i:], provider.subs[i+1:])
+			provider.subs = provider.subs[:len(provider.subs)-1]
+			continue
+		}
+
+		select {
+		case sub.sendChan <- event:
+		default:
+			provider.dropped.Add(1)
+			sub.handle.AddDropped()
+		}
+		i++
+	}
+}
+
+// returns the amount of events that could not be sent to a subscriber since
+// the provider was created, see Emit
+func (provider *LossyEventProvider[T]) GetDroppedCount() uint64 {
+	return provider.dropped.Load()
+}
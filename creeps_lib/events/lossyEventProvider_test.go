@@ -0,0 +1,44 @@
+package events
+
+import "testing"
+
+func TestLossyEventProviderDrops(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		emitted  int
+		dropped  uint64
+	}{
+		{name: "room left", capacity: 4, emitted: 3, dropped: 0},
+		{name: "exactly full", capacity: 4, emitted: 4, dropped: 0},
+		{name: "overflow", capacity: 4, emitted: 10, dropped: 6},
+		{name: "no buffer", capacity: 0, emitted: 2, dropped: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var provider LossyEventProvider[int]
+			channel := make(chan int, test.capacity)
+			handle := provider.Subscribe(channel)
+			// never read, must not be affected by the other one
+			other := provider.Subscribe(make(chan int, test.emitted))
+
+			for i := 0; i < test.emitted; i++ {
+				provider.Emit(i)
+			}
+
+			if got := handle.GetDroppedCount(); got != test.dropped {
+				t.Errorf("expected %d dropped for the subscriber, got %d", test.dropped, got)
+			}
+			if got := other.GetDroppedCount(); got != 0 {
+				t.Errorf("expected nothing dropped for the other subscriber, got %d", got)
+			}
+			if got := provider.GetDroppedCount(); got != test.dropped {
+				t.Errorf("expected %d dropped in total, got %d", test.dropped, got)
+			}
+			if len(channel) != test.emitted-int(test.dropped) {
+				t.Errorf("expected %d events received, got %d", test.emitted-int(test.dropped), len(channel))
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package spatialevents
 import (
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/heavenston/creeps_server/creeps_lib/events"
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
@@ -11,9 +13,11 @@ import (
 )
 
 type sub[T any] struct {
-	filter   AABB
-	sendChan chan T
-	handle   *events.CancelHandle
+	filter AABB
+	// only gets the events with an empty aabb, see SubscribeGlobal
+	globalOnly bool
+	sendChan   chan T
+	handle     *events.CancelHandle
 
 	file string
 	line int
@@ -30,6 +34,17 @@ func (sub sub[T]) GetAABB() AABB {
 // like events.EventProvider but filtered by position
 type SpatialEventProvider[T spatialmap.Spatialized] struct {
 	subs spatialmap.SpatialMap[sub[T]]
+
+	// if set, applied to every event before it is sent (ex: to add the tick
+	// at which it was emitted), must be set before anything is emitted
+	Stamp func(event T) T
+
+	// events not sent because the channel of the subscriber was full
+	dropped atomic.Uint64
+
+	listenersLock sync.RWMutex
+	// see AddListener
+	listeners []func(event T)
 }
 
 func NewSpatialEventProvider[T spatialmap.Spatialized]() *SpatialEventProvider[T] {
@@ -52,36 +67,83 @@ func (provider *SpatialEventProvider[T]) SubscribeWithHandle(
 	channel chan T,
 	filter AABB,
 	handle *events.CancelHandle,
+) {
+	provider.subscribe(channel, filter, false, handle)
+}
+
+// only sends the events that are not bound to a position (their aabb is
+// empty, ex: player or ticker events) instead of every events like a
+// subscription with an empty filter would
+func (provider *SpatialEventProvider[T]) SubscribeGlobal(
+	channel chan T,
+) *events.CancelHandle {
+	handle := new(events.CancelHandle)
+	provider.subscribe(channel, AABB{}, true, handle)
+	return handle
+}
+
+func (provider *SpatialEventProvider[T]) subscribe(
+	channel chan T,
+	filter AABB,
+	globalOnly bool,
+	handle *events.CancelHandle,
 ) {
 	if handle.IsCancelled() {
 		return
 	}
 
-	_, file, line, _ := runtime.Caller(1)
+	_, file, line, _ := runtime.Caller(2)
 	if strings.Contains(file, "spatialevents") {
-		_, file, line, _ = runtime.Caller(2)
+		_, file, line, _ = runtime.Caller(3)
 	}
 
 	provider.subs.Add(sub[T]{
-		sendChan: channel,
-		handle:   handle,
-		filter:   filter,
-		file:     file,
-		line:     line,
+		sendChan:   channel,
+		handle:     handle,
+		filter:     filter,
+		globalOnly: globalOnly,
+		file:       file,
+		line:       line,
 	})
 }
 
+// the listener is called with every event by Emit, synchronously and before
+// the subscribers get it, so unlike them it never misses one and sees them in
+// the order they were emitted
+// it must not block as it delays the emitter (usually the ticker)
+func (provider *SpatialEventProvider[T]) AddListener(listener func(event T)) {
+	provider.listenersLock.Lock()
+	defer provider.listenersLock.Unlock()
+	provider.listeners = append(provider.listeners, listener)
+}
+
 func (provider *SpatialEventProvider[T]) Emit(event T) {
+	if provider.Stamp != nil {
+		event = provider.Stamp(event)
+	}
 	aabb := event.GetAABB()
 
+	// copied as listeners can emit events themselves
+	provider.listenersLock.RLock()
+	listeners := provider.listeners
+	provider.listenersLock.RUnlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+
 	provider.subs.RemoveAll(func(t sub[T]) bool {
 		return t.handle.IsCancelled()
 	})
 
 	for _, sub := range provider.subs.GetAllIntersects(aabb) {
+		if sub.globalOnly && !aabb.IsZero() {
+			continue
+		}
 		select {
 		case sub.sendChan <- event:
 		default:
+			provider.dropped.Add(1)
+			sub.handle.AddDropped()
 			log.Warn().
 				Type("event_type", event).
 				Str("sub_file", sub.file).
@@ -90,3 +152,9 @@ func (provider *SpatialEventProvider[T]) Emit(event T) {
 		}
 	}
 }
+
+// returns the amount of events that could not be sent to a subscriber since
+// the provider was created, see Emit
+func (provider *SpatialEventProvider[T]) GetDroppedCount() uint64 {
+	return provider.dropped.Load()
+}
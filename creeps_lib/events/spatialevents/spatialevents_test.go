@@ -0,0 +1,70 @@
+package spatialevents
+
+import (
+	"testing"
+
+	"github.com/heavenston/creeps_server/creeps_lib/events"
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/spatialmap"
+)
+
+type testEvent struct {
+	aabb AABB
+}
+
+func (event *testEvent) MovementEvents() *events.EventProvider[spatialmap.ObjectMovedEvent] {
+	return nil
+}
+
+func (event *testEvent) GetAABB() AABB {
+	return event.aabb
+}
+
+func TestSubscriptionFilters(t *testing.T) {
+	tile := AABB{From: Point{X: 2, Y: 3}, Size: Point{X: 1, Y: 1}}
+	far := AABB{From: Point{X: 100, Y: 100}, Size: Point{X: 1, Y: 1}}
+
+	tests := []struct {
+		name string
+		// the aabbs of the emitted events
+		emitted []AABB
+		// the amount of events received by each subscription
+		all, area, global int
+	}{
+		{name: "global", emitted: []AABB{{}}, all: 1, area: 1, global: 1},
+		{name: "in the area", emitted: []AABB{tile}, all: 1, area: 1, global: 0},
+		{name: "outside of the area", emitted: []AABB{far}, all: 1, area: 0, global: 0},
+		{name: "mixed", emitted: []AABB{tile, {}, far, {}}, all: 4, area: 3, global: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := NewSpatialEventProvider[*testEvent]()
+			all := make(chan *testEvent, len(test.emitted))
+			area := make(chan *testEvent, len(test.emitted))
+			global := make(chan *testEvent, len(test.emitted))
+			provider.Subscribe(all, AABB{})
+			provider.Subscribe(area, AABB{Size: Point{X: 10, Y: 10}})
+			provider.SubscribeGlobal(global)
+
+			for _, aabb := range test.emitted {
+				provider.Emit(&testEvent{aabb: aabb})
+			}
+
+			if len(all) != test.all {
+				t.Errorf("expected %d events without filter, got %d", test.all, len(all))
+			}
+			if len(area) != test.area {
+				t.Errorf("expected %d events in the area, got %d", test.area, len(area))
+			}
+			if len(global) != test.global {
+				t.Errorf("expected %d global events, got %d", test.global, len(global))
+			}
+			for len(global) > 0 {
+				if aabb := (<-global).GetAABB(); !aabb.IsZero() {
+					t.Errorf("received the non global event %v", aabb)
+				}
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package geom
 
+import mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
+
 // An AxisAlignedBoundingBox, size must be position or else functions will return invalid results
 // (but size ~can~ be 0 -> contains no point and not contained in anything)
 type AABB struct {
@@ -33,3 +35,13 @@ func (aabb AABB) Intersects(other AABB) bool {
 	return aabb.From.X < other.Upto().X && aabb.From.Y < other.Upto().Y &&
 		other.From.X < aabb.Upto().X && other.From.Y < aabb.Upto().Y
 }
+
+// returns the point inside the aabb closest to the given one
+// (the aabb must not be empty)
+func (aabb AABB) Clamp(p Point) Point {
+	upto := aabb.Upto()
+	return Point{
+		X: mathutils.Max(aabb.From.X, mathutils.Min(p.X, upto.X-1)),
+		Y: mathutils.Max(aabb.From.Y, mathutils.Min(p.Y, upto.Y-1)),
+	}
+}
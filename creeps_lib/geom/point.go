@@ -43,6 +43,14 @@ type Point struct {
 	Y int `json:"y"`
 }
 
+// orders points in row-major order, to be used with slices.SortFunc
+func ComparePoints(a Point, b Point) int {
+	if a.Y != b.Y {
+		return a.Y - b.Y
+	}
+	return a.X - b.X
+}
+
 func (p Point) ToAtomic() AtomicPoint {
 	return AtomicPoint{
 		point: p,
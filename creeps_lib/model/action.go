@@ -35,6 +35,9 @@ const (
 
 	OpCodeFireTurret    = "fire:turret"
 	OpCodeFireBomberBot = "fire:bomber-bot"
+
+	OpCodeMessageSend  = "message:send"
+	OpCodeMessageFetch = "message:fetch"
 )
 
 func (opcode ActionOpCode) IsValid() bool {
@@ -81,6 +84,10 @@ func (opcode ActionOpCode) IsValid() bool {
 		return true
 	case OpCodeFireBomberBot:
 		return true
+	case OpCodeMessageSend:
+		return true
+	case OpCodeMessageFetch:
+		return true
 	}
 	return false
 }
@@ -134,6 +141,11 @@ func (opcode ActionOpCode) GetReportType() reflect.Type {
 		fallthrough
 	case OpCodeFireBomberBot:
 		return reflect.TypeFor[FireReport]()
+
+	case OpCodeMessageSend:
+		return reflect.TypeFor[MessageSendReport]()
+	case OpCodeMessageFetch:
+		return reflect.TypeFor[MessageFetchReport]()
 	}
 	panic("invalid opcode")
 }
@@ -182,6 +194,10 @@ func (opcode ActionOpCode) GetCost(costs *CostsResponse, upgradeCost *CostRespon
 		return &costs.FireTurret
 	case OpCodeFireBomberBot:
 		return &costs.FireBomberBot
+	case OpCodeMessageSend:
+		return &costs.SendMessage
+	case OpCodeMessageFetch:
+		return &costs.FetchMessage
 	}
 	panic("invalid opcode")
 }
@@ -222,6 +238,8 @@ func (opcode ActionOpCode) ParameterType() reflect.Type {
 		return reflect.TypeFor[FireParameter]()
 	case OpCodeFireBomberBot:
 		return reflect.TypeFor[FireParameter]()
+	case OpCodeMessageSend:
+		return reflect.TypeFor[MessageSendParameter]()
 	default:
 		return nil
 	}
@@ -57,25 +57,117 @@ type CostsResponse struct {
 	UpgradeTurret    CostResponse `json:"upgradeTurret"`
 }
 
+// Describes how enemies get stronger, the difficulty is computed as
+// 1 + PerTick * tick + PerBuilding * buildings + PerUnit * units
+// and caped to Max
+// The enemy spawn rates are divided by the difficulty while raid sizes and
+// raider strength are multiplied by it
+type DifficultySetup struct {
+	PerTick     float64 `json:"perTick"`
+	PerBuilding float64 `json:"perBuilding"`
+	PerUnit     float64 `json:"perUnit"`
+	// 0 means no maximum
+	Max float64 `json:"max"`
+}
+
+// Multipliers of the move cast time when moving from or to tiles of the given
+// kinds, the cast is multiplied by the average of the source's and
+// destination's factors
+// 0 means no change (same as 1)
+type MoveFactors struct {
+	Road float64 `json:"road"`
+	Bush float64 `json:"bush"`
+	Tree float64 `json:"tree"`
+}
+
+// Weights of what makes the score of a player in a match, the score is
+// PerUnit * units + PerBuilding * buildings + PerAchievement * achievements
+// + PerResource * (sum of all resources)
+type ScoreSetup struct {
+	PerUnit        float64 `json:"perUnit"`
+	PerBuilding    float64 `json:"perBuilding"`
+	PerAchievement float64 `json:"perAchievement"`
+	PerResource    float64 `json:"perResource"`
+}
+
+// What happens when a unit or a player exceeds its maximum amount of misses,
+// see SetupResponse.MaxMissesPerPlayer and SetupResponse.MaxMissesPerUnit
+type MissPenalty string
+
+const (
+	// kills the unit of the failed command (nothing happens if it had none)
+	MissPenaltyKillUnit MissPenalty = "killUnit"
+	// kills the player and everything it owns
+	MissPenaltyDisconnect MissPenalty = "disconnect"
+)
+
+func (penalty MissPenalty) IsValid() bool {
+	return penalty == MissPenaltyKillUnit || penalty == MissPenaltyDisconnect
+}
+
+// Lifecycle of a match: waiting for MinPlayers, counting down, running until
+// an end condition is met and finished
+type MatchSetup struct {
+	// if disabled the game runs as soon as the server starts and never ends
+	Enabled bool `json:"enabled"`
+	// players needed to start the countdown (at least 1)
+	MinPlayers int `json:"minPlayers"`
+	// seconds between enough players joining and the start of the match
+	CountdownSeconds int `json:"countdownSeconds"`
+	// ends the match when only one of the players is alive (or none if there
+	// was only one)
+	LastPlayerAlive bool `json:"lastPlayerAlive"`
+	// ends the match after this many ticks of running (0 = no limit)
+	TickLimit int `json:"tickLimit"`
+	// ends the match when a player reaches this score (0 = no threshold)
+	ScoreThreshold float64    `json:"scoreThreshold"`
+	Score          ScoreSetup `json:"score"`
+}
+
 type SetupResponse struct {
 	CitizenFeedingRate int  `json:"citizenFeedingRate"`
 	EnableGC           bool `json:"enableGC"`
-	GcTickRate         int  `json:"gcTickRate"`
-	EnableEnemies      bool `json:"enableEnemies"`
-	EnemyTickRate      int  `json:"enemyTickRate"`
+	// ticks between two collections of the gc, 0 disables it entirely
+	GcTickRate int `json:"gcTickRate"`
+	// disabled from json for epita compitibility
+	// ticks after which reports are removed by the gc (0 = never)
+	GcReportMaxAge int `json:"-"`
+	// disabled from json for epita compitibility
+	// ticks after which an idle unit is killed if EnableGC is set (0 = never)
+	GcUnitMaxIdle int  `json:"-"`
+	EnableEnemies bool `json:"enableEnemies"`
+	EnemyTickRate int  `json:"enemyTickRate"`
+	// disabled from json for epita compitibility
+	EnemyBaseTickRate int `json:"-"`
+	// disabled from json for epita compitibility
+	// amount of raiders spawned by a raid at difficulty 1
+	EnemyRaidSize int `json:"-"`
+	// disabled from json for epita compitibility
+	EnemyDifficulty    DifficultySetup `json:"-"`
+	FoodGatherRate     int             `json:"foodGatherRate"`
+	MaxLoad            int             `json:"maxLoad"`
+	MaxMissesPerPlayer int             `json:"maxMissesPerPlayer"`
+	MaxMissesPerUnit   int             `json:"maxMissesPerUnit"`
+	// disabled from json for epita compitibility
+	MissPenalty MissPenalty `json:"-"`
+	// disabled from json for epita compitibility
+	MoveFactors MoveFactors `json:"-"`
+	// disabled from json for epita compitibility
+	// maximum amount of units on the same tile (0 = no limit)
+	MaxUnitsPerTile int    `json:"-"`
+	OilGatherRate   int    `json:"oilGatherRate"`
+	RockGatherRate  int    `json:"rockGatherRate"`
+	ServerId        string `json:"serverId"`
+	// 0 when the server runs as fast as possible
+	TicksPerSecond    float64 `json:"ticksPerSecond"`
+	TrackAchievements bool    `json:"trackAchievements"`
+	WoodGatherRate    int     `json:"woodGatherRate"`
+	// disabled from json for epita compitibility
+	// if set units cannot go outside of WorldDimension, see WorldBounds
+	BoundedWorld   bool       `json:"-"`
+	WorldDimension geom.Point `json:"worldDimension"`
 	// disabled from json for epita compitibility
-	EnemyBaseTickRate  int        `json:"-"`
-	FoodGatherRate     int        `json:"foodGatherRate"`
-	MaxLoad            int        `json:"maxLoad"`
-	MaxMissesPerPlayer int        `json:"maxMissesPerPlayer"`
-	MaxMissesPerUnit   int        `json:"maxMissesPerUnit"`
-	OilGatherRate      int        `json:"oilGatherRate"`
-	RockGatherRate     int        `json:"rockGatherRate"`
-	ServerId           string     `json:"serverId"`
-	TicksPerSecond     float64    `json:"ticksPerSecond"`
-	TrackAchievements  bool       `json:"trackAchievements"`
-	WoodGatherRate     int        `json:"woodGatherRate"`
-	WorldDimension     geom.Point `json:"worldDimension"`
+	Match MatchSetup `json:"-"`
 }
 
 type InitResponse struct {
@@ -90,6 +182,8 @@ type InitResponse struct {
 	Setup                *SetupResponse `json:"setup"`
 	Tick                 int            `json:"tick"`
 	TownHallCoordinates  *geom.Point    `json:"townHallCoordinates"`
+	// to be sent with every command and report request, see the README
+	Token *string `json:"token,omitempty"`
 }
 
 type CommandResponse struct {
@@ -36,6 +36,10 @@ type Report struct {
 	Login        string       `json:"login"`
 	UnitPosition geom.Point   `json:"unitPosition"`
 	Status       string       `json:"status"`
+	// tick at which the action was started
+	StartedAtTick int `json:"startedAtTick"`
+	// tick at which the action was resolved
+	FinishedAtTick int `json:"finishedAtTick"`
 }
 
 func (r *Report) GetReport() *Report {
@@ -61,6 +65,8 @@ type ObserveReport struct {
 type MoveReport struct {
 	ObserveReport
 	NewPosition geom.Point `json:"newPosition"`
+	// effective cast time of the move (depends on the tiles)
+	Cast int `json:"cast"`
 }
 
 type GatherReport struct {
@@ -99,6 +105,8 @@ type SpawnReport struct {
 
 type DismantleReport struct {
 	Report
+	Building          Building  `json:"building"`
+	RefundedResources Resources `json:"refundedResources"`
 }
 
 type UpgradeReport struct {
@@ -107,6 +115,9 @@ type UpgradeReport struct {
 
 type RefineReport struct {
 	Report
+	// disabled from json for epita compitibility
+	// amount of the refined resource produced
+	Produced int `json:"-"`
 }
 
 type MessageSendReport struct {
@@ -121,6 +132,7 @@ type MessageFetchReport struct {
 
 type FireReport struct {
 	Report
-	Target      geom.Point `json:"target"`
-	KilledUnits []Unit     `json:"killedUnits"`
+	Target             geom.Point `json:"target"`
+	KilledUnits        []Unit     `json:"killedUnits"`
+	DestroyedBuildings []Building `json:"destroyedBuildings,omitempty"`
 }
@@ -107,6 +107,17 @@ func (res Resources) Sum(other Resources) Resources {
 	return res
 }
 
+// divides (rounding down) every resources by the given value
+func (res Resources) Div(divisor int) Resources {
+	res.Rock /= divisor
+	res.Wood /= divisor
+	res.Food /= divisor
+	res.Oil /= divisor
+	res.Copper /= divisor
+	res.WoodPlank /= divisor
+	return res
+}
+
 func (res Resources) Size() int {
 	return res.Rock + res.Wood + res.Food + res.Oil + res.Copper + res.WoodPlank
 }
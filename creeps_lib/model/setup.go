@@ -0,0 +1,21 @@
+package model
+
+import "github.com/heavenston/creeps_server/creeps_lib/geom"
+
+// Gets the area of size WorldDimension centered on the origin
+// only enforced if BoundedWorld is set
+func (setup *SetupResponse) WorldBounds() geom.AABB {
+	return geom.AABB{
+		From: geom.Point{
+			X: -setup.WorldDimension.X / 2,
+			Y: -setup.WorldDimension.Y / 2,
+		},
+		Size: setup.WorldDimension,
+	}
+}
+
+// returns true if the given point is inside of the world bounds
+// always true if the world isn't bounded
+func (setup *SetupResponse) IsInWorld(p geom.Point) bool {
+	return !setup.BoundedWorld || setup.WorldBounds().Contains(p)
+}
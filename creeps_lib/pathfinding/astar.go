@@ -0,0 +1,112 @@
+package pathfinding
+
+import (
+	"container/heap"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+)
+
+var directions = [4]Point{
+	{X: 1, Y: 0},
+	{X: -1, Y: 0},
+	{X: 0, Y: 1},
+	{X: 0, Y: -1},
+}
+
+type node struct {
+	point Point
+	// cost from the start
+	cost int
+	// cost + heuristic
+	priority int
+}
+
+// min-heap of nodes by priority, see container/heap
+type openSet []node
+
+func (s openSet) Len() int           { return len(s) }
+func (s openSet) Less(i, j int) bool { return s[i].priority < s[j].priority }
+func (s openSet) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s *openSet) Push(x any)        { *s = append(*s, x.(node)) }
+func (s *openSet) Pop() (popped any) {
+	old := *s
+	popped = old[len(old)-1]
+	*s = old[:len(old)-1]
+	return
+}
+
+// Finds the shortest path from 'from' to 'to' only going through tiles for
+// which walkable returns true (the start tile doesn't need to be walkable)
+// using A* with 4-directional movements.
+//
+// Returns the path as the list of points to go through, excluding 'from' and
+// including 'to', or false if no path was found in less than maxVisited
+// visited tiles (used to avoid exploring the infinite map)
+func FindPath(
+	from Point,
+	to Point,
+	walkable func(p Point) bool,
+	maxVisited int,
+) ([]Point, bool) {
+	if from == to {
+		return []Point{}, true
+	}
+
+	cameFrom := make(map[Point]Point)
+	costs := map[Point]int{from: 0}
+
+	open := &openSet{{point: from, cost: 0, priority: from.Dist(to)}}
+
+	visited := 0
+	for open.Len() > 0 {
+		current := heap.Pop(open).(node)
+		// outdated entry (a better path to this point was found after)
+		if current.cost > costs[current.point] {
+			continue
+		}
+
+		if current.point == to {
+			return reconstruct(cameFrom, from, to), true
+		}
+
+		visited++
+		if visited > maxVisited {
+			return nil, false
+		}
+
+		for _, dir := range directions {
+			next := current.point.Add(dir)
+			cost := current.cost + 1
+
+			if known, ok := costs[next]; ok && known <= cost {
+				continue
+			}
+			if !walkable(next) {
+				continue
+			}
+
+			costs[next] = cost
+			cameFrom[next] = current.point
+			heap.Push(open, node{
+				point:    next,
+				cost:     cost,
+				priority: cost + next.Dist(to),
+			})
+		}
+	}
+
+	return nil, false
+}
+
+func reconstruct(cameFrom map[Point]Point, from Point, to Point) []Point {
+	path := make([]Point, 0)
+	for current := to; current != from; current = cameFrom[current] {
+		path = append(path, current)
+	}
+
+	// reverse
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,148 @@
+package pathfinding
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+)
+
+// parses a map where '#' are walls, 'S' the start and 'E' the end (the start
+// if there is none), the first line being y = 0, everything outside of it is
+// a wall
+func parseGrid(t *testing.T, grid string) (walkable func(p Point) bool, from Point, to Point) {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSpace(grid), "\n")
+	walls := make(map[Point]bool)
+	hasEnd := false
+	for y, line := range lines {
+		for x, c := range strings.TrimSpace(line) {
+			p := Point{X: x, Y: y}
+			switch c {
+			case '#':
+				walls[p] = true
+			case 'S':
+				from = p
+			case 'E':
+				to = p
+				hasEnd = true
+			}
+		}
+	}
+	if !hasEnd {
+		to = from
+	}
+
+	width := len(strings.TrimSpace(lines[0]))
+	walkable = func(p Point) bool {
+		if p.X < 0 || p.Y < 0 || p.X >= width || p.Y >= len(lines) {
+			return false
+		}
+		return !walls[p]
+	}
+	return
+}
+
+func TestFindPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		grid       string
+		maxVisited int
+		// -1 if no path must be found
+		length int
+	}{
+		{
+			name: "same tile",
+			grid: `
+				S.
+			`,
+			maxVisited: 10,
+			length:     0,
+		},
+		{
+			name: "straight line",
+			grid: `
+				S...E
+			`,
+			maxVisited: 10,
+			length:     4,
+		},
+		{
+			name: "around a wall",
+			grid: `
+				.....
+				.S#E.
+				..#..
+			`,
+			maxVisited: 20,
+			length:     4,
+		},
+		{
+			name: "maze",
+			grid: `
+				S#...
+				.#.#.
+				.#.#.
+				...#E
+			`,
+			maxVisited: 20,
+			length:     13,
+		},
+		{
+			name: "walled in",
+			grid: `
+				S#..
+				##.E
+			`,
+			maxVisited: 20,
+			length:     -1,
+		},
+		{
+			name: "too far",
+			grid: `
+				S.........E
+			`,
+			maxVisited: 5,
+			length:     -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			walkable, from, to := parseGrid(t, test.grid)
+
+			path, found := FindPath(from, to, walkable, test.maxVisited)
+			if test.length < 0 {
+				if found {
+					t.Fatalf("expected no path, got %v", path)
+				}
+				return
+			}
+			if !found {
+				t.Fatalf("expected a path of length %d, got none", test.length)
+			}
+			if len(path) != test.length {
+				t.Fatalf("expected a path of length %d, got %v", test.length, path)
+			}
+
+			previous := from
+			for _, p := range path {
+				if previous.Dist(p) != 1 {
+					t.Fatalf("path %v jumps from %v to %v", path, previous, p)
+				}
+				if !walkable(p) {
+					t.Fatalf("path %v goes through the wall at %v", path, p)
+				}
+				previous = p
+			}
+			if len(path) > 0 && path[len(path)-1] != to {
+				t.Fatalf("path %v does not end at %v", path, to)
+			}
+			if slices.Contains(path, from) {
+				t.Fatalf("path %v contains the start", path)
+			}
+		})
+	}
+}
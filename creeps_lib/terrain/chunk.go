@@ -26,6 +26,8 @@ type Chunk struct {
 	tileslock            sync.RWMutex
 	tiles                [ChunkTileCount]Tile
 	UpdatedEventProvider events.EventProvider[any]
+	// the events of the tilemap the chunk is in, can be nil
+	tilemapEvents *events.LossyEventProvider[TilemapChunkEvent]
 }
 
 type ReadLockedChunk struct {
@@ -88,6 +90,16 @@ func (chunk *Chunk) IsGenerated() bool {
 	return chunk.isGenerated.Load()
 }
 
+// Copies all tiles of the chunk in row-major order
+func (chunk *Chunk) GetTiles() []Tile {
+	chunk.tileslock.RLock()
+	defer chunk.tileslock.RUnlock()
+
+	tiles := make([]Tile, ChunkTileCount)
+	copy(tiles, chunk.tiles[:])
+	return tiles
+}
+
 func (chunk *Chunk) IsInBounds(subcoord Point) bool {
 	return subcoord.X >= 0 && subcoord.X < ChunkSize ||
 		subcoord.Y >= 0 || subcoord.Y < ChunkSize
@@ -131,7 +143,7 @@ func (chunk *Chunk) ModifyTile(subcoord Point, cb func(Tile) Tile) Tile {
 	chunk.tileslock.Unlock()
 
 	if newValue != prevValue {
-		chunk.UpdatedEventProvider.Emit(TileUpdateChunkEvent{
+		chunk.emit(TileUpdateChunkEvent{
 			UpdatedPosition: subcoord,
 			PreviousValue:   prevValue,
 			NewValue:        newValue,
@@ -141,6 +153,17 @@ func (chunk *Chunk) ModifyTile(subcoord Point, cb func(Tile) Tile) Tile {
 	return prevValue
 }
 
+// emits the event to the chunk's subscribers and the tilemap's ones
+func (chunk *Chunk) emit(event any) {
+	chunk.UpdatedEventProvider.Emit(event)
+	if chunk.tilemapEvents != nil {
+		chunk.tilemapEvents.Emit(TilemapChunkEvent{
+			ChunkPos: chunk.chunkPos,
+			Event:    event,
+		})
+	}
+}
+
 func (chunk *Chunk) Print(w io.Writer) {
 	rlc := chunk.RLock()
 	defer rlc.UnLock()
@@ -30,6 +30,9 @@ const (
 
 	// Used when the tile is not generated
 	TileUnknown
+
+	// Generated outside of the world bounds, nothing can go through it
+	TileWorldBorder
 )
 
 func TileFromResource(res model.ResourceKind) TileKind {
@@ -121,5 +124,9 @@ func (tile Tile) Print(w io.Writer) {
 	case TileUnknown:
 		c.Add(color.Reset)
 		c.Fprint(w, "XX")
+	case TileWorldBorder:
+		c.Add(color.BgBlack)
+		c.Add(color.FgWhite)
+		c.Fprint(w, "##")
 	}
 }
@@ -6,10 +6,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/heavenston/creeps_server/creeps_lib/events"
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
 	"github.com/rs/zerolog/log"
 )
 
+// emitted by the tilemap for every event of any of its chunks
+type TilemapChunkEvent struct {
+	ChunkPos Point
+	// TileUpdateChunkEvent or GeneratedChunkEvent
+	Event any
+	// tick at which it was emitted, only set if the owner of the tilemap
+	// stamps the events (see events.LossyEventProvider.Stamp)
+	Tick int
+}
+
 type Tilemap struct {
 	// guards chunks
 	chunkslock sync.RWMutex
@@ -17,6 +28,10 @@ type Tilemap struct {
 	// chunkslock
 	generator IGenerator
 	chunks    map[Point]*Chunk
+	// receives the events of every chunk, see TilemapChunkEvent
+	// chunks are modified from many goroutines so events are dropped rather
+	// than blocking them
+	UpdatedEventProvider events.LossyEventProvider[TilemapChunkEvent]
 }
 
 // generator can be nil in which case the default generator will be used
@@ -61,12 +76,46 @@ func (tilemap *Tilemap) CreateChunk(chunkPos Point) *Chunk {
 	chunk := tilemap.chunks[chunkPos]
 	if chunk == nil {
 		chunk = NewChunk(chunkPos)
+		chunk.tilemapEvents = &tilemap.UpdatedEventProvider
 		tilemap.chunks[chunkPos] = chunk
 	}
 
 	return chunk
 }
 
+// Returns every generated chunk, mainly for serialization
+func (tilemap *Tilemap) GetGeneratedChunks() []*Chunk {
+	tilemap.chunkslock.RLock()
+	defer tilemap.chunkslock.RUnlock()
+
+	chunks := make([]*Chunk, 0, len(tilemap.chunks))
+	for _, chunk := range tilemap.chunks {
+		if chunk.IsGenerated() {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// Replaces the content of the chunk at the given position with the given
+// tiles (in row-major order) without using the generator, used to restore
+// saved chunks
+func (tilemap *Tilemap) RestoreChunk(chunkPos Point, tiles []Tile) error {
+	if len(tiles) != ChunkTileCount {
+		return fmt.Errorf("expected %d tiles, got %d", ChunkTileCount, len(tiles))
+	}
+
+	chunk := tilemap.CreateChunk(chunkPos)
+
+	wc := chunk.WLock()
+	copy(chunk.tiles[:], tiles)
+	chunk.isGenerated.Store(true)
+	wc.UnLock()
+
+	chunk.emit(GeneratedChunkEvent{})
+	return nil
+}
+
 // Like GetChunk but if it would return nil this will generate the chunk using
 // the assigned generator.
 func (tilemap *Tilemap) GenerateChunk(chunkPos Point) *Chunk {
@@ -92,7 +141,7 @@ func (tilemap *Tilemap) GenerateChunk(chunkPos Point) *Chunk {
 
 	wc.UnLock()
 
-	chunk.UpdatedEventProvider.Emit(GeneratedChunkEvent{})
+	chunk.emit(GeneratedChunkEvent{})
 	return chunk
 }
 
@@ -3,6 +3,7 @@ package uid
 import (
 	"math/rand"
 	"strings"
+	"sync"
 )
 
 type Uid string
@@ -12,12 +13,44 @@ const ServerUid Uid = "_server"
 
 var alphabet string = "abcdefghijklmnopqstuvwxyzABCDEFGHIJKLMNOPQSTUVWXYZ"
 
-func GenUid() Uid {
+// generates uids from its own source, so seeding one (ex: the one of a
+// server) does not change the uids of the others
+// the zero value uses the global source until Seed is called
+type Generator struct {
+	// guards seededRand
+	lock sync.Mutex
+	// nil until Seed is called, in which case the global source is used
+	seededRand *rand.Rand
+}
+
+// Makes all uids generated after the call a reproducible sequence
+func (gen *Generator) Seed(seed int64) {
+	gen.lock.Lock()
+	defer gen.lock.Unlock()
+	gen.seededRand = rand.New(rand.NewSource(seed))
+}
+
+func (gen *Generator) GenUid() Uid {
+	gen.lock.Lock()
+	defer gen.lock.Unlock()
+
+	intn := rand.Intn
+	if gen.seededRand != nil {
+		intn = gen.seededRand.Intn
+	}
+
 	result := strings.Builder{}
 
 	for i := 0; i < 10; i++ {
-		result.WriteByte(alphabet[rand.Intn(len(alphabet))])
+		result.WriteByte(alphabet[intn(len(alphabet))])
 	}
 
 	return Uid(result.String())
 }
+
+// never seeded, see Generator to get a reproducible sequence
+var global Generator
+
+func GenUid() Uid {
+	return global.GenUid()
+}
@@ -0,0 +1,38 @@
+package uid
+
+import "testing"
+
+func genAll(gen *Generator, count int) []Uid {
+	uids := make([]Uid, count)
+	for i := range uids {
+		uids[i] = gen.GenUid()
+	}
+	return uids
+}
+
+// two generators with the same seed give the same sequence even when used
+// at the same time, like the servers of two games with the same seed
+func TestSeededGenerators(t *testing.T) {
+	var a, b Generator
+	a.Seed(42)
+	b.Seed(42)
+
+	var fromA, fromB []Uid
+	for i := 0; i < 10; i++ {
+		fromA = append(fromA, genAll(&a, 2)...)
+		GenUid()
+		fromB = append(fromB, genAll(&b, 2)...)
+	}
+
+	for i := range fromA {
+		if fromA[i] != fromB[i] {
+			t.Fatalf("uid %d differs: %s and %s", i, fromA[i], fromB[i])
+		}
+	}
+
+	var other Generator
+	other.Seed(43)
+	if genAll(&other, 1)[0] == fromA[0] {
+		t.Errorf("expected another seed to give other uids")
+	}
+}
@@ -0,0 +1,113 @@
+package achievements
+
+import (
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/rs/zerolog/log"
+)
+
+// names of the achievements, sent as is to the clients
+const (
+	FirstSawmill   = "first-sawmill"
+	FirstSmeltery  = "first-smeltery"
+	SecondTownHall = "second-town-hall"
+	TenCitizens    = "ten-citizens"
+	RaidSurvivor   = "raid-survivor"
+	CopperRefiner  = "copper-refiner"
+	FirstBomberBot = "first-bomber-bot"
+)
+
+// how much copper must be refined for the CopperRefiner achievement
+const copperRefinerAmount = 100
+
+// how many citizens must be alive at the same time for TenCitizens
+const tenCitizensCount = 10
+
+type tracker struct {
+	server *server.Server
+}
+
+// Starts awarding achievements to the players of the given server
+// the events are handled as they are emitted (see
+// SpatialEventProvider.AddListener) so none is missed and achievements are
+// awarded in a reproducible order
+// should only be called once per server, usually if setup.TrackAchievements
+// is enabled
+func Track(srv *server.Server) {
+	t := &tracker{
+		server: srv,
+	}
+	srv.Events().AddListener(t.handleEvent)
+
+	log.Info().Msg("Tracking achievements")
+}
+
+func (t *tracker) ownerPlayer(unit server.IUnit) *entities.Player {
+	player, _ := t.server.GetEntity(unit.GetOwner()).(*entities.Player)
+	return player
+}
+
+func (t *tracker) handleEvent(event server.IServerEvent) {
+	switch e := event.(type) {
+	case *server.UnitFinishedActionEvent:
+		t.handleFinishedAction(e)
+	case *server.UnitSpawnEvent:
+		if e.Unit.GetOpCode() != "citizen" {
+			break
+		}
+		player := t.ownerPlayer(e.Unit)
+		if player == nil {
+			break
+		}
+
+		citizens := 0
+		player.ForEachEntities(func(entity server.IEntity) (shouldStop bool) {
+			if unit, ok := entity.(server.IUnit); ok && unit.GetOpCode() == "citizen" {
+				citizens++
+			}
+			return
+		})
+		if citizens >= tenCitizensCount {
+			player.AddAchievement(TenCitizens)
+		}
+	case *entities.RaidFinishedEvent:
+		if !e.Defeated {
+			break
+		}
+		player, ok := t.server.GetEntity(e.Raid.GetOwner()).(*entities.Player)
+		if ok && player.IsRegistered() {
+			player.AddAchievement(RaidSurvivor)
+		}
+	}
+}
+
+func (t *tracker) handleFinishedAction(e *server.UnitFinishedActionEvent) {
+	if e.Report == nil || e.Report.GetReport().Status != "SUCCESS" {
+		return
+	}
+	player := t.ownerPlayer(e.Unit)
+	if player == nil {
+		return
+	}
+
+	switch e.Action.OpCode {
+	case model.OpCodeBuildSawmill:
+		player.AddAchievement(FirstSawmill)
+	case model.OpCodeBuildSmeltery:
+		player.AddAchievement(FirstSmeltery)
+	case model.OpCodeBuildTownHall:
+		player.AddAchievement(SecondTownHall)
+	case model.OpCodeSpawnBomberBot:
+		player.AddAchievement(FirstBomberBot)
+	case model.OpCodeRefineCopper:
+		report, ok := e.Report.(*model.RefineReport)
+		if !ok {
+			break
+		}
+
+		if player.AddRefinedCopper(report.Produced) >= copperRefinerAmount {
+			player.AddAchievement(CopperRefiner)
+		}
+	}
+}
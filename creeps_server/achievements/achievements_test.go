@@ -0,0 +1,115 @@
+package achievements
+
+import (
+	"slices"
+	"testing"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+// emits the event of a successful action of the unit
+func finishAction(srv *server.Server, unit server.IUnit, opcode model.ActionOpCode, report model.IReport) {
+	report.GetReport().Status = "SUCCESS"
+	srv.Events().Emit(&server.UnitFinishedActionEvent{
+		Unit:   unit,
+		Pos:    unit.GetPosition(),
+		Action: &server.Action{OpCode: opcode},
+		Report: report,
+	})
+}
+
+func TestAchievements(t *testing.T) {
+	tests := []struct {
+		name string
+		// copper already refined by the player, as if restored from a
+		// snapshot
+		refinedCopper int
+		// called with the first citizen of the player
+		act      func(srv *server.Server, citizen server.IUnit)
+		expected []string
+	}{
+		{
+			name:     "nothing",
+			act:      func(srv *server.Server, citizen server.IUnit) {},
+			expected: []string{},
+		},
+		{
+			name: "in order",
+			act: func(srv *server.Server, citizen server.IUnit) {
+				finishAction(srv, citizen, model.OpCodeBuildSmeltery, &model.BuildReport{})
+				finishAction(srv, citizen, model.OpCodeBuildSawmill, &model.BuildReport{})
+				finishAction(srv, citizen, model.OpCodeBuildSawmill, &model.BuildReport{})
+			},
+			expected: []string{FirstSmeltery, FirstSawmill},
+		},
+		{
+			name: "failed actions",
+			act: func(srv *server.Server, citizen server.IUnit) {
+				srv.Events().Emit(&server.UnitFinishedActionEvent{
+					Unit:   citizen,
+					Action: &server.Action{OpCode: model.OpCodeBuildSawmill},
+					Report: &model.ErrorReport{Report: model.Report{Status: "ERROR"}},
+				})
+			},
+			expected: []string{},
+		},
+		{
+			name: "copper refiner",
+			act: func(srv *server.Server, citizen server.IUnit) {
+				for i := 0; i < copperRefinerAmount; i++ {
+					finishAction(srv, citizen, model.OpCodeRefineCopper, &model.RefineReport{Produced: 1})
+				}
+			},
+			expected: []string{CopperRefiner},
+		},
+		{
+			name: "not enough copper",
+			act: func(srv *server.Server, citizen server.IUnit) {
+				for i := 0; i < copperRefinerAmount-1; i++ {
+					finishAction(srv, citizen, model.OpCodeRefineCopper, &model.RefineReport{Produced: 1})
+				}
+			},
+			expected: []string{},
+		},
+		{
+			name:          "copper refined before a restore",
+			refinedCopper: copperRefinerAmount - 1,
+			act: func(srv *server.Server, citizen server.IUnit) {
+				finishAction(srv, citizen, model.OpCodeRefineCopper, &model.RefineReport{Produced: 1})
+			},
+			expected: []string{CopperRefiner},
+		},
+		{
+			name: "ten citizens",
+			act: func(srv *server.Server, citizen server.IUnit) {
+				// the player starts with two
+				for i := 0; i < tenCitizensCount-2; i++ {
+					spawned := entities.NewCitizenUnit(srv, citizen.GetOwner())
+					spawned.SetPosition(citizen.GetPosition())
+					spawned.Register()
+				}
+			},
+			expected: []string{TenCitizens},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := servertest.NewServer(0)
+			Track(srv)
+			player, citizen, _ := servertest.SpawnPlayer(srv, "alice", Point{})
+			player.AddRefinedCopper(test.refinedCopper)
+
+			test.act(srv, citizen)
+
+			// awarded synchronously, no need to wait
+			if got := player.GetAchievements(); !slices.Equal(got, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package admin_api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Http api for game masters to intervene in a running game, on its own
+// listener so it can be kept private
+// every request must have an `Authorization: Bearer <Secret>` header
+type AdminServer struct {
+	Server *server.Server
+	Addr   string
+	// must not be empty, Start refuses to run otherwise
+	Secret string
+	// nil if the game is not played as a match
+	Match *match.Match
+}
+
+type errorResponse struct {
+	ErrorCode string `json:"errorCode"`
+	Error     string `json:"error"`
+}
+
+func writeJson(w http.ResponseWriter, status int, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		w.WriteHeader(500)
+		log.Error().Err(err).Msg("ADMIN: response ser error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, mess string) {
+	writeJson(w, status, errorResponse{
+		ErrorCode: code,
+		Error:     mess,
+	})
+}
+
+// reads the json body into the given value, writes the error response and
+// returns false if it is invalid
+func readBody(w http.ResponseWriter, r *http.Request, into any) bool {
+	err := json.NewDecoder(r.Body).Decode(into)
+	if err != nil {
+		writeError(w, 400, "invalidbody", "Cannot deserialize the body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// logs the action of an admin request (with its address), meant to be used
+// once the action succeeded
+func logAction(r *http.Request) *zerolog.Event {
+	return log.Info().
+		Str("addr", r.RemoteAddr).
+		Str("method", r.Method).
+		Str("path", r.URL.Path)
+}
+
+func (admin *AdminServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if admin.Secret == "" ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(admin.Secret)) != 1 {
+			log.Warn().
+				Str("addr", r.RemoteAddr).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Msg("ADMIN: Unauthorized request")
+			writeError(w, 401, "unauthorized", "Missing or invalid admin secret")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (admin *AdminServer) Start() {
+	if admin.Secret == "" {
+		log.Error().Msg("Admin api has no secret, not starting")
+		return
+	}
+
+	handler := admin.Handler()
+
+	log.Info().Str("addr", admin.Addr).Msg("Admin api starting")
+
+	http.ListenAndServe(admin.Addr, handler)
+}
+
+// returns the handler of every route of the admin api, to be served on Addr
+// by Start or mounted by a game manager
+// every request is refused if the secret is empty
+func (admin *AdminServer) Handler() http.Handler {
+	router := chi.NewRouter()
+	router.Use(middleware.RealIP)
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.Timeout(60 * time.Second))
+	router.Use(admin.authenticate)
+
+	router.Get("/players", admin.listPlayers)
+	router.Post("/players/{username}/kick", admin.kickPlayer)
+	router.Post("/players/{username}/resources", admin.grantResources)
+	router.Post("/players/{username}/raid", admin.triggerRaid)
+
+	router.Delete("/entities/{id}", admin.killEntity)
+	router.Put("/tiles", admin.setTiles)
+
+	router.Post("/ticker/pause", admin.pauseTicker)
+	router.Post("/ticker/resume", admin.resumeTicker)
+	router.Post("/ticker/step", admin.stepTicker)
+	router.Post("/ticker/tps", admin.setTickerTps)
+
+	router.Get("/match", admin.getMatch)
+	router.Post("/match/start", admin.startMatch)
+	router.Post("/match/finish", admin.finishMatch)
+
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, 404, "notfound", "Admin endpoint does not exist")
+	})
+
+	return router
+}
@@ -0,0 +1,58 @@
+package admin_api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/heavenston/creeps_server/creeps_server/match"
+)
+
+// writes an error and returns false if the game is not played as a match
+func (admin *AdminServer) checkMatch(w http.ResponseWriter) bool {
+	if admin.Match == nil {
+		writeError(w, 404, "nomatch", "The game is not played as a match")
+		return false
+	}
+	return true
+}
+
+func (admin *AdminServer) getMatch(w http.ResponseWriter, r *http.Request) {
+	if !admin.checkMatch(w) {
+		return
+	}
+	writeJson(w, 200, admin.Match.GetStatus())
+}
+
+// starts the match now, even if there are not enough players
+func (admin *AdminServer) startMatch(w http.ResponseWriter, r *http.Request) {
+	if !admin.checkMatch(w) {
+		return
+	}
+	err := admin.Match.Start()
+	if errors.Is(err, match.ErrAlreadyStarted) {
+		writeError(w, 409, "matchstarted", err.Error())
+		return
+	}
+	if errors.Is(err, match.ErrAlreadyFinished) {
+		writeError(w, 409, "matchfinished", err.Error())
+		return
+	}
+
+	logAction(r).Msg("ADMIN: Started the match")
+	writeJson(w, 200, admin.Match.GetStatus())
+}
+
+// finishes the match now, the results are produced as usual
+func (admin *AdminServer) finishMatch(w http.ResponseWriter, r *http.Request) {
+	if !admin.checkMatch(w) {
+		return
+	}
+	err := admin.Match.Finish()
+	if errors.Is(err, match.ErrAlreadyFinished) {
+		writeError(w, 409, "matchfinished", err.Error())
+		return
+	}
+
+	logAction(r).Msg("ADMIN: Finished the match")
+	writeJson(w, 200, admin.Match.GetStatus())
+}
@@ -0,0 +1,150 @@
+package admin_api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+)
+
+type playerResponse struct {
+	Id         uid.Uid         `json:"id"`
+	Username   string          `json:"username"`
+	Addr       string          `json:"addr"`
+	SpawnPoint Point           `json:"spawnPoint"`
+	Resources  model.Resources `json:"resources"`
+	Units      int             `json:"units"`
+	Buildings  int             `json:"buildings"`
+}
+
+type raidResponse struct {
+	Id             uid.Uid `json:"id"`
+	CampPosition   Point   `json:"campPosition"`
+	TargetPosition Point   `json:"targetPosition"`
+}
+
+func newPlayerResponse(player *entities.Player) playerResponse {
+	return playerResponse{
+		Id:         player.GetId(),
+		Username:   player.GetUsername(),
+		Addr:       player.GetAddr(),
+		SpawnPoint: player.GetSpawnPoint(),
+		Resources:  player.GetResources(),
+		Units:      player.GetUnitCount(),
+		Buildings:  player.GetBuildingCount(),
+	}
+}
+
+// finds the player of the username url parameter, writes the error response
+// and returns nil if there is none
+func (admin *AdminServer) urlPlayer(w http.ResponseWriter, r *http.Request) *entities.Player {
+	username := chi.URLParam(r, "username")
+	player, _ := admin.Server.FindEntity(func(e server.IEntity) bool {
+		if p, ok := e.(*entities.Player); ok {
+			return p.GetUsername() == username
+		}
+		return false
+	}).(*entities.Player)
+	if player == nil {
+		writeError(w, 404, "noplayer", "No player is named '"+username+"'")
+	}
+	return player
+}
+
+func (admin *AdminServer) listPlayers(w http.ResponseWriter, r *http.Request) {
+	players := make([]playerResponse, 0)
+	all := make([]*entities.Player, 0)
+	admin.Server.ForEachEntity(func(entity server.IEntity) (shouldStop bool) {
+		if player, ok := entity.(*entities.Player); ok {
+			all = append(all, player)
+		}
+		return
+	})
+	// the player functions cannot be called while iterating
+	server.SortEntities(all)
+	for _, player := range all {
+		players = append(players, newPlayerResponse(player))
+	}
+
+	writeJson(w, 200, players)
+}
+
+// kills the player and everything it owns
+func (admin *AdminServer) kickPlayer(w http.ResponseWriter, r *http.Request) {
+	player := admin.urlPlayer(w, r)
+	if player == nil {
+		return
+	}
+
+	player.Unregister()
+
+	logAction(r).
+		Str("username", player.GetUsername()).
+		Str("player_id", string(player.GetId())).
+		Msg("ADMIN: Kicked player")
+	writeJson(w, 200, newPlayerResponse(player))
+}
+
+// adds the resources of the body (which can be negative) to the player's
+func (admin *AdminServer) grantResources(w http.ResponseWriter, r *http.Request) {
+	player := admin.urlPlayer(w, r)
+	if player == nil {
+		return
+	}
+	var granted model.Resources
+	if !readBody(w, r, &granted) {
+		return
+	}
+
+	enough := true
+	player.ModifyResources(func(res model.Resources) model.Resources {
+		sum := res.Sum(granted)
+		if sum.Rock < 0 || sum.Wood < 0 || sum.Food < 0 ||
+			sum.Oil < 0 || sum.Copper < 0 || sum.WoodPlank < 0 {
+			enough = false
+			return res
+		}
+		return sum
+	})
+	if !enough {
+		writeError(w, 400, "notenough", "The player does not have the resources to remove")
+		return
+	}
+
+	logAction(r).
+		Str("username", player.GetUsername()).
+		Any("granted", granted).
+		Any("resources", player.GetResources()).
+		Msg("ADMIN: Granted resources")
+	writeJson(w, 200, newPlayerResponse(player))
+}
+
+// starts a raid against the player right away
+func (admin *AdminServer) triggerRaid(w http.ResponseWriter, r *http.Request) {
+	player := admin.urlPlayer(w, r)
+	if player == nil {
+		return
+	}
+
+	raid := entities.NewRaid(admin.Server, player.GetId())
+	raid.Register()
+	if !raid.IsRegistered() {
+		writeError(w, 409, "noroom", "No room was found for the raid camp")
+		return
+	}
+
+	logAction(r).
+		Str("username", player.GetUsername()).
+		Str("raid_id", string(raid.GetId())).
+		Any("camp_position", raid.GetCampPosition()).
+		Msg("ADMIN: Triggered raid")
+	writeJson(w, 200, raidResponse{
+		Id:             raid.GetId(),
+		CampPosition:   raid.GetCampPosition(),
+		TargetPosition: raid.GetTargetPosition(),
+	})
+}
@@ -0,0 +1,181 @@
+package admin_api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+)
+
+type tileRequest struct {
+	Position Point            `json:"position"`
+	Kind     terrain.TileKind `json:"kind"`
+	Value    uint8            `json:"value"`
+}
+
+type entityResponse struct {
+	Id    uid.Uid `json:"id"`
+	Type  string  `json:"type"`
+	Owner uid.Uid `json:"owner"`
+}
+
+type stepRequest struct {
+	Ticks int `json:"ticks"`
+}
+
+type tickerStateResponse struct {
+	TicksPerSecond float64 `json:"ticksPerSecond"`
+	Paused         bool    `json:"paused"`
+}
+
+type tpsRequest struct {
+	// 0 for as fast as possible
+	TicksPerSecond float64 `json:"ticksPerSecond"`
+}
+
+// unregisters any entity (unit, player, raid...), killing what it owns
+func (admin *AdminServer) killEntity(w http.ResponseWriter, r *http.Request) {
+	id := uid.Uid(chi.URLParam(r, "id"))
+	entity := admin.Server.GetEntity(id)
+	if entity == nil {
+		writeError(w, 404, "noentity", "No entity has this id")
+		return
+	}
+
+	entity.Unregister()
+
+	response := entityResponse{
+		Id:    id,
+		Type:  fmt.Sprintf("%T", entity),
+		Owner: entity.GetOwner(),
+	}
+	if unit, ok := entity.(server.IUnit); ok {
+		response.Type = unit.GetOpCode()
+	}
+
+	logAction(r).
+		Str("entity_id", string(id)).
+		Str("entity_type", response.Type).
+		Str("owner_id", string(response.Owner)).
+		Msg("ADMIN: Killed entity")
+	writeJson(w, 200, response)
+}
+
+// sets every tile of the body, which is a list of tiles
+func (admin *AdminServer) setTiles(w http.ResponseWriter, r *http.Request) {
+	var tiles []tileRequest
+	if !readBody(w, r, &tiles) {
+		return
+	}
+
+	for _, tile := range tiles {
+		if tile.Kind >= terrain.TileUnknown && tile.Kind != terrain.TileWorldBorder {
+			writeError(w, 400, "invalidkind", fmt.Sprintf("Tile kind %d does not exist", tile.Kind))
+			return
+		}
+	}
+
+	for _, tile := range tiles {
+		previous := admin.Server.Tilemap().SetTile(tile.Position, terrain.Tile{
+			Kind:  tile.Kind,
+			Value: tile.Value,
+		})
+		logAction(r).
+			Any("position", tile.Position).
+			Uint8("previous_kind", uint8(previous.Kind)).
+			Uint8("kind", uint8(tile.Kind)).
+			Uint8("value", tile.Value).
+			Msg("ADMIN: Set tile")
+	}
+
+	writeJson(w, 200, struct {
+		Count int `json:"count"`
+	}{len(tiles)})
+}
+
+func (admin *AdminServer) writeTickerState(w http.ResponseWriter) {
+	state := admin.Server.Ticker().GetState()
+	writeJson(w, 200, tickerStateResponse{
+		TicksPerSecond: state.TicksPerSecond,
+		Paused:         state.Paused,
+	})
+}
+
+// writes an error and returns false if the match refused to control the
+// ticker (match.ErrNotRunning), as it pauses and resumes it itself until it
+// runs
+func (admin *AdminServer) checkTickerControl(w http.ResponseWriter, err error) bool {
+	if err != nil {
+		writeError(w, 409, "matchnotrunning", "The ticker is controlled by the match until it runs")
+		return false
+	}
+	return true
+}
+
+func (admin *AdminServer) pauseTicker(w http.ResponseWriter, r *http.Request) {
+	var err error
+	if admin.Match != nil {
+		err = admin.Match.Pause()
+	} else {
+		admin.Server.Ticker().Pause()
+	}
+	if !admin.checkTickerControl(w, err) {
+		return
+	}
+	logAction(r).Msg("ADMIN: Paused ticker")
+	admin.writeTickerState(w)
+}
+
+func (admin *AdminServer) resumeTicker(w http.ResponseWriter, r *http.Request) {
+	var err error
+	if admin.Match != nil {
+		err = admin.Match.Resume()
+	} else {
+		admin.Server.Ticker().Resume()
+	}
+	if !admin.checkTickerControl(w, err) {
+		return
+	}
+	logAction(r).Msg("ADMIN: Resumed ticker")
+	admin.writeTickerState(w)
+}
+
+func (admin *AdminServer) stepTicker(w http.ResponseWriter, r *http.Request) {
+	var step stepRequest
+	if !readBody(w, r, &step) {
+		return
+	}
+	if step.Ticks <= 0 {
+		writeError(w, 400, "invalidticks", "The amount of ticks must be positive")
+		return
+	}
+	var err error
+	if admin.Match != nil {
+		err = admin.Match.Step(step.Ticks)
+	} else {
+		admin.Server.Ticker().Step(step.Ticks)
+	}
+	if !admin.checkTickerControl(w, err) {
+		return
+	}
+	logAction(r).Int("ticks", step.Ticks).Msg("ADMIN: Stepped ticker")
+	admin.writeTickerState(w)
+}
+
+func (admin *AdminServer) setTickerTps(w http.ResponseWriter, r *http.Request) {
+	var tps tpsRequest
+	if !readBody(w, r, &tps) {
+		return
+	}
+	if tps.TicksPerSecond < 0 {
+		writeError(w, 400, "invalidtps", "The ticks per second must not be negative")
+		return
+	}
+	admin.Server.Ticker().SetTicksPerSecond(tps.TicksPerSecond)
+	logAction(r).Float64("tps", tps.TicksPerSecond).Msg("ADMIN: Changed ticker speed")
+	admin.writeTickerState(w)
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"gopkg.in/yaml.v3"
+)
+
+// prefix of the environment variables overriding config values, the rest of
+// the name is the upper-cased key with dots replaced by underscores
+// (ex: CREEPS_SETUP_TICKSPERSECOND or CREEPS_COSTS_BUILDROAD_ROCK)
+const EnvPrefix = "CREEPS"
+
+// name (without extension) of the config file looked for in SearchPaths
+const FileName = "heavcreeps"
+
+// extensions of the config file looked for in SearchPaths, json is read as
+// yaml
+var Extensions = []string{".yaml", ".yml", ".json"}
+
+// searched in order when no config file is given
+var SearchPaths = []string{
+	".",
+	"$HOME/.heavcreeps",
+	"/etc/heavcreeps",
+}
+
+// Everything that can be set in the config file
+// keys are the field names in camelCase (ex: setup.ticksPerSecond), the
+// resources of a cost are at the same level as its cast
+// (ex: costs.buildRoad.rock)
+type Config struct {
+	Setup model.SetupResponse
+	Costs model.CostsResponse
+	// resources players start with
+	PlayerResources model.Resources
+}
+
+// Reads the config from the given file (yaml or json, not toml) or from the search
+// paths if empty, then applies environment variables overrides
+// fields not set anywhere keep their default value
+// every unknown key, invalid value and validation problem is reported at
+// once
+// returns the path of the file used (empty if none was found)
+func Load(path string) (*Config, string, error) {
+	if path == "" {
+		path = findFile()
+	}
+
+	config := Default()
+	value := reflect.ValueOf(&config).Elem()
+	errs := make([]error, 0)
+
+	if path != "" {
+		// would be read as (invalid) yaml otherwise
+		if strings.EqualFold(filepath.Ext(path), ".toml") {
+			return nil, "", fmt.Errorf("%s: toml config files are not supported, use yaml or json", path)
+		}
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		values := make(map[string]any)
+		err = yaml.Unmarshal(bytes, &values)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", path, err)
+		}
+		decode(&errs, "", values, value)
+	}
+
+	applyEnv(&errs, "", value)
+
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, "", err
+	}
+
+	return &config, path, nil
+}
+
+// returns the first config file found in SearchPaths, empty if none
+func findFile() string {
+	for _, searchPath := range SearchPaths {
+		for _, ext := range Extensions {
+			path := filepath.Join(os.ExpandEnv(searchPath), FileName+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// sets the fields of the given struct from the values read in the file,
+// keys are matched ignoring case and embedded structs are squashed
+func decode(errs *[]error, prefix string, values map[string]any, target reflect.Value) {
+	fields := make(map[string]reflect.Value)
+	collectFields(fields, target)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	// reports the errors in a stable order
+	slices.Sort(keys)
+
+	for _, name := range keys {
+		key := joinKey(prefix, name)
+		field, ok := fields[strings.ToLower(name)]
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%s: unknown key", key))
+			continue
+		}
+
+		raw := values[name]
+		if field.Kind() == reflect.Struct {
+			sub, ok := raw.(map[string]any)
+			if !ok {
+				*errs = append(*errs, fmt.Errorf("%s: expected a mapping (got %v)", key, raw))
+				continue
+			}
+			decode(errs, key, sub, field)
+			continue
+		}
+
+		err := setValue(field, raw)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+}
+
+// indexes the fields of the struct by their lower-cased name, with the ones
+// of embedded structs
+func collectFields(fields map[string]reflect.Value, target reflect.Value) {
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		if field.Anonymous {
+			collectFields(fields, target.Field(i))
+			continue
+		}
+		fields[strings.ToLower(field.Name)] = target.Field(i)
+	}
+}
+
+// sets a value read from the file, integers are accepted for floats
+func setValue(field reflect.Value, raw any) error {
+	switch field.Kind() {
+	case reflect.Int:
+		if v, ok := raw.(int); ok {
+			field.SetInt(int64(v))
+			return nil
+		}
+		return fmt.Errorf("expected an integer (got %v)", raw)
+	case reflect.Float64:
+		switch v := raw.(type) {
+		case int:
+			field.SetFloat(float64(v))
+			return nil
+		case float64:
+			field.SetFloat(v)
+			return nil
+		}
+		return fmt.Errorf("expected a number (got %v)", raw)
+	case reflect.Bool:
+		if v, ok := raw.(bool); ok {
+			field.SetBool(v)
+			return nil
+		}
+		return fmt.Errorf("expected a boolean (got %v)", raw)
+	case reflect.String:
+		if v, ok := raw.(string); ok {
+			field.SetString(v)
+			return nil
+		}
+		return fmt.Errorf("expected a string (got %v)", raw)
+	}
+	return fmt.Errorf("unsupported type %s", field.Type())
+}
+
+// overrides the fields with the environment variables named after their key,
+// see EnvPrefix
+func applyEnv(errs *[]error, prefix string, target reflect.Value) {
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		fieldValue := target.Field(i)
+
+		key := prefix
+		// embedded structs are squashed
+		if !field.Anonymous {
+			key = joinKey(prefix, field.Name)
+		}
+
+		if fieldValue.Kind() == reflect.Struct {
+			applyEnv(errs, key, fieldValue)
+			continue
+		}
+
+		name := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		err := setString(fieldValue, env)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s (from %s): %w", key, name, err))
+		}
+	}
+}
+
+// sets a value read from an environment variable
+func setString(field reflect.Value, str string) error {
+	switch field.Kind() {
+	case reflect.Int:
+		v, err := strconv.Atoi(str)
+		if err != nil {
+			return fmt.Errorf("expected an integer (got %q)", str)
+		}
+		field.SetInt(int64(v))
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number (got %q)", str)
+		}
+		field.SetFloat(v)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(str)
+		if err != nil {
+			return fmt.Errorf("expected a boolean (got %q)", str)
+		}
+		field.SetBool(v)
+	case reflect.String:
+		field.SetString(str)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+func joinKey(prefix string, name string) string {
+	name = strings.ToLower(name[:1]) + name[1:]
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// Checks that the values make sense, every problem is reported with the key
+// of the field
+func (config *Config) Validate() error {
+	errs := make([]error, 0)
+
+	// nothing in the config can be negative
+	checkNotNegative(&errs, "", reflect.ValueOf(*config))
+
+	// used as divisors or sizes
+	positive := []struct {
+		key   string
+		value int
+	}{
+		{"setup.citizenFeedingRate", config.Setup.CitizenFeedingRate},
+		{"setup.enemyTickRate", config.Setup.EnemyTickRate},
+		{"setup.enemyBaseTickRate", config.Setup.EnemyBaseTickRate},
+		{"setup.maxLoad", config.Setup.MaxLoad},
+		{"setup.worldDimension.x", config.Setup.WorldDimension.X},
+		{"setup.worldDimension.y", config.Setup.WorldDimension.Y},
+	}
+	for _, p := range positive {
+		// negative values are already reported
+		if p.value == 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be zero", p.key))
+		}
+	}
+
+	if config.Setup.Match.Enabled && config.Setup.Match.MinPlayers == 0 {
+		errs = append(errs, errors.New("setup.match.minPlayers: must not be zero"))
+	}
+
+	if !config.Setup.MissPenalty.IsValid() {
+		errs = append(errs, fmt.Errorf(
+			"setup.missPenalty: must be %q or %q (got %q)",
+			model.MissPenaltyKillUnit, model.MissPenaltyDisconnect, config.Setup.MissPenalty,
+		))
+	}
+
+	if config.Setup.ServerId == "" {
+		errs = append(errs, errors.New("setup.serverId: must not be empty"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkNotNegative(errs *[]error, prefix string, value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldValue := value.Field(i)
+
+		key := prefix
+		if !field.Anonymous {
+			key = joinKey(prefix, field.Name)
+		}
+
+		switch fieldValue.Kind() {
+		case reflect.Struct:
+			checkNotNegative(errs, key, fieldValue)
+		case reflect.Int:
+			if fieldValue.Int() < 0 {
+				*errs = append(*errs, fmt.Errorf("%s: must not be negative (got %d)", key, fieldValue.Int()))
+			}
+		case reflect.Float64:
+			if fieldValue.Float() < 0 {
+				*errs = append(*errs, fmt.Errorf("%s: must not be negative (got %g)", key, fieldValue.Float()))
+			}
+		}
+	}
+}
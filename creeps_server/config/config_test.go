@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		// name of the written config file, its extension matters
+		file    string
+		content string
+		env     map[string]string
+		// called if no error is expected
+		check func(t *testing.T, config *Config)
+		// every one must be in the error, nil if none is expected
+		errors []string
+	}{
+		{
+			name:    "yaml",
+			file:    "config.yaml",
+			content: "setup:\n  ticksPerSecond: 10\n  enemyDifficulty:\n    perTick: 0.5\ncosts:\n  buildRoad:\n    rock: 2\n    cast: 3\nplayerResources:\n  food: 50\n",
+			check: func(t *testing.T, config *Config) {
+				defaults := Default()
+				if config.Setup.TicksPerSecond != 10 {
+					t.Errorf("setup.ticksPerSecond: expected 10, got %g", config.Setup.TicksPerSecond)
+				}
+				if config.Setup.EnemyDifficulty.PerTick != 0.5 {
+					t.Errorf("setup.enemyDifficulty.perTick: expected 0.5, got %g", config.Setup.EnemyDifficulty.PerTick)
+				}
+				if config.Costs.BuildRoad.Rock != 2 || config.Costs.BuildRoad.Cast != 3 {
+					t.Errorf("costs.buildRoad: expected 2 rock and a cast of 3, got %+v", config.Costs.BuildRoad)
+				}
+				if config.PlayerResources.Food != 50 {
+					t.Errorf("playerResources.food: expected 50, got %d", config.PlayerResources.Food)
+				}
+				if config.Costs.BuildRoad.Wood != defaults.Costs.BuildRoad.Wood {
+					t.Errorf("costs.buildRoad.wood: expected the default, got %d", config.Costs.BuildRoad.Wood)
+				}
+				if config.Setup.MaxLoad != defaults.Setup.MaxLoad {
+					t.Errorf("setup.maxLoad: expected the default, got %d", config.Setup.MaxLoad)
+				}
+			},
+		},
+		{
+			name:    "json with any case",
+			file:    "config.json",
+			content: `{"Setup": {"TICKSPERSECOND": 2, "missPenalty": "disconnect"}}`,
+			check: func(t *testing.T, config *Config) {
+				if config.Setup.TicksPerSecond != 2 {
+					t.Errorf("setup.ticksPerSecond: expected 2, got %g", config.Setup.TicksPerSecond)
+				}
+				if config.Setup.MissPenalty != model.MissPenaltyDisconnect {
+					t.Errorf("setup.missPenalty: expected disconnect, got %q", config.Setup.MissPenalty)
+				}
+			},
+		},
+		{
+			name:    "environment overrides the file",
+			file:    "config.yaml",
+			content: "setup:\n  ticksPerSecond: 10\n",
+			env: map[string]string{
+				"CREEPS_SETUP_TICKSPERSECOND": "3",
+				"CREEPS_COSTS_BUILDROAD_ROCK": "7",
+				"CREEPS_SETUP_ENABLEENEMIES":  "false",
+			},
+			check: func(t *testing.T, config *Config) {
+				if config.Setup.TicksPerSecond != 3 {
+					t.Errorf("setup.ticksPerSecond: expected 3, got %g", config.Setup.TicksPerSecond)
+				}
+				if config.Costs.BuildRoad.Rock != 7 {
+					t.Errorf("costs.buildRoad.rock: expected 7, got %d", config.Costs.BuildRoad.Rock)
+				}
+				if config.Setup.EnableEnemies {
+					t.Errorf("setup.enableEnemies: expected false")
+				}
+			},
+		},
+		{
+			name:    "zero gcTickRate disables the gc",
+			file:    "config.yaml",
+			content: "setup:\n  gcTickRate: 0\n",
+			check: func(t *testing.T, config *Config) {
+				if config.Setup.GcTickRate != 0 {
+					t.Errorf("setup.gcTickRate: expected 0, got %d", config.Setup.GcTickRate)
+				}
+			},
+		},
+		{
+			name:    "every decoding error",
+			file:    "config.yaml",
+			content: "setup:\n  ticksPerSecond: fast\n  notAKey: 1\n  enableEnemies: 1\ncosts: 3\n",
+			env: map[string]string{
+				"CREEPS_SETUP_MAXLOAD": "heavy",
+			},
+			errors: []string{
+				"setup.ticksPerSecond: expected a number",
+				"setup.notAKey: unknown key",
+				"setup.enableEnemies: expected a boolean",
+				"costs: expected a mapping",
+				"setup.maxLoad (from CREEPS_SETUP_MAXLOAD): expected an integer",
+			},
+		},
+		{
+			name:    "toml",
+			file:    "config.toml",
+			content: "[setup]\nticksPerSecond = 10\n",
+			errors:  []string{"toml config files are not supported"},
+		},
+		{
+			name:    "every validation error",
+			file:    "config.yaml",
+			content: "setup:\n  ticksPerSecond: -1\n  maxLoad: 0\n  missPenalty: explode\n  serverId: \"\"\ncosts:\n  buildRoad:\n    rock: -2\n",
+			errors: []string{
+				"setup.ticksPerSecond: must not be negative",
+				"setup.maxLoad: must not be zero",
+				"setup.missPenalty: must be",
+				"setup.serverId: must not be empty",
+				"costs.buildRoad.rock: must not be negative",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			path := filepath.Join(t.TempDir(), test.file)
+			err := os.WriteFile(path, []byte(test.content), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config, usedPath, err := Load(path)
+			if test.errors == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if usedPath != path {
+					t.Errorf("expected the path %s, got %s", path, usedPath)
+				}
+				test.check(t, config)
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected errors %v, got none", test.errors)
+			}
+			for _, expected := range test.errors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected %q in the error, got:\n%v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	config := Default()
+	err := config.Validate()
+	if err != nil {
+		t.Fatalf("the default config is invalid: %v", err)
+	}
+}
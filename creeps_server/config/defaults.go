@@ -0,0 +1,201 @@
+package config
+
+import (
+	"math"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+)
+
+var defaultSetup model.SetupResponse = model.SetupResponse{
+	CitizenFeedingRate: 25,
+	EnableGC:           false,
+	GcTickRate:         150,
+	GcReportMaxAge:     1500,
+	GcUnitMaxIdle:      3000,
+	EnableEnemies:      true,
+	EnemyTickRate:      8,
+	EnemyBaseTickRate:  300,
+	EnemyRaidSize:      5,
+	EnemyDifficulty: model.DifficultySetup{
+		PerTick:     1. / 3000.,
+		PerBuilding: 0.05,
+		PerUnit:     0.02,
+		Max:         10,
+	},
+	MaxLoad:            20,
+	MaxMissesPerPlayer: 200,
+	MaxMissesPerUnit:   200,
+	MissPenalty:        model.MissPenaltyKillUnit,
+	MoveFactors: model.MoveFactors{
+		Road: 0.5,
+	},
+	ServerId:          "heavenstone_server",
+	TicksPerSecond:    5,
+	TrackAchievements: false,
+	WorldDimension: Point{
+		// big value but leave two bits to avoid any overflow anywhere
+		X: math.MaxInt32 >> 2,
+		Y: math.MaxInt32 >> 2,
+	},
+	FoodGatherRate: 5,
+	OilGatherRate:  2,
+	RockGatherRate: 5,
+	WoodGatherRate: 5,
+	Match: model.MatchSetup{
+		Enabled:          false,
+		MinPlayers:       2,
+		CountdownSeconds: 10,
+		LastPlayerAlive:  true,
+		Score: model.ScoreSetup{
+			PerUnit:        10,
+			PerBuilding:    25,
+			PerAchievement: 100,
+			PerResource:    0.1,
+		},
+	},
+}
+
+var defaultCosts model.CostsResponse = model.CostsResponse{
+	BuildHousehold: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 10,
+			Wood: 10,
+		},
+		Cast: 6,
+	},
+	BuildRoad: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 1,
+		},
+		Cast: 2,
+	},
+	BuildSawmill: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 15,
+			Wood: 25,
+		},
+		Cast: 10,
+	},
+	BuildSmeltery: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 25,
+			Wood: 15,
+		},
+		Cast: 2,
+	},
+	BuildTownHall: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 100,
+			Wood: 100,
+		},
+		Cast: 20,
+	},
+	Dismantle: model.CostResponse{
+		Cast: 1,
+	},
+	Farm: model.CostResponse{
+		Cast: 10,
+	},
+	FetchMessage: model.CostResponse{
+		Cast: 1,
+	},
+	FireBomberBot: model.CostResponse{
+		Cast: 6,
+	},
+	FireTurret: model.CostResponse{
+		Cast: 2,
+	},
+	Gather: model.CostResponse{
+		Cast: 4,
+	},
+	Move: model.CostResponse{
+		Cast: 2,
+	},
+	Noop: model.CostResponse{
+		Cast: 1,
+	},
+	Observe: model.CostResponse{
+		Cast: 1,
+	},
+	RefineCopper: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 10,
+		},
+		Cast: 8,
+	},
+	RefineWoodPlank: model.CostResponse{
+		Resources: model.Resources{
+			Wood: 10,
+		},
+		Cast: 8,
+	},
+	SendMessage: model.CostResponse{
+		Cast: 1,
+	},
+	SpawnBomberBot: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 5,
+			Wood: 10,
+		},
+		Cast: 6,
+	},
+	SpawnTurret: model.CostResponse{
+		Resources: model.Resources{
+			Rock: 10,
+			Wood: 5,
+		},
+		Cast: 6,
+	},
+	Unload: model.CostResponse{
+		Cast: 1,
+	},
+	UpgradeBomberBot: model.CostResponse{
+		Resources: model.Resources{
+			Rock:      5,
+			Wood:      10,
+			Oil:       4,
+			Copper:    1,
+			WoodPlank: 2,
+		},
+		Cast: 1,
+	},
+	UpgradeCitizen: model.CostResponse{
+		Resources: model.Resources{
+			Rock:      5,
+			Wood:      5,
+			Food:      2,
+			Copper:    1,
+			WoodPlank: 1,
+		},
+		Cast: 1,
+	},
+	UpgradeTurret: model.CostResponse{
+		Resources: model.Resources{
+			Rock:      10,
+			Wood:      5,
+			Oil:       4,
+			Copper:    3,
+			WoodPlank: 1,
+		},
+		Cast: 1,
+	},
+}
+
+var defaultPlayerResources model.Resources = model.Resources{
+	Rock:      30,
+	Wood:      30,
+	Food:      30,
+	Oil:       0,
+	Copper:    0,
+	WoodPlank: 0,
+}
+
+// the values used for everything the config does not set
+func Default() Config {
+	return Config{
+		Setup:           defaultSetup,
+		Costs:           defaultCosts,
+		PlayerResources: defaultPlayerResources,
+	}
+}
@@ -4,17 +4,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
 	. "github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
 	"github.com/rs/zerolog/log"
 )
 
 type ApiServer struct {
 	Server *Server
 	Addr   string
+	// legacy mode, requests without a token are authenticated by comparing
+	// their ip with the one that called /init
+	AllowAddrAuth bool
+	// served on /metrics, metrics.Default if nil
+	Metrics *metrics.Registry
+	// nil if the game is not played as a match, otherwise players can only
+	// join before it starts and commands are refused unless it runs
+	Match *match.Match
+
+	// held during /init so two players cannot get the same username
+	initLock sync.Mutex
+
+	playersLock sync.RWMutex
+	// every player ever created by /init in order, dead or alive
+	players []*entities.Player
+
+	// see registerMetrics
+	commandsTotal      *metrics.CounterVec
+	commandErrorsTotal *metrics.CounterVec
 }
 
 type ApiErrorResponse struct {
@@ -22,7 +45,41 @@ type ApiErrorResponse struct {
 	Error     string `json:"error"`
 }
 
+func (api *ApiServer) addPlayer(player *entities.Player) {
+	api.playersLock.Lock()
+	defer api.playersLock.Unlock()
+	api.players = append(api.players, player)
+}
+
+func (api *ApiServer) copyPlayers() []*entities.Player {
+	api.playersLock.RLock()
+	defer api.playersLock.RUnlock()
+
+	players := make([]*entities.Player, len(api.players))
+	copy(players, api.players)
+	return players
+}
+
 func (api *ApiServer) Start() {
+	handler := api.Handler()
+
+	log.Info().Str("addr", api.Addr).Msg("Api server starting")
+
+	http.ListenAndServe(api.Addr, handler)
+}
+
+// returns the handler of every route of the api, to be served on Addr by
+// Start or mounted by a game manager
+// must only be called once
+func (api *ApiServer) Handler() http.Handler {
+	// players restored from a snapshot were not created by /init
+	api.Server.ForEachEntity(func(entity IEntity) (shouldStop bool) {
+		if player, ok := entity.(*entities.Player); ok {
+			api.addPlayer(player)
+		}
+		return
+	})
+
 	router := chi.NewRouter()
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
@@ -36,6 +93,26 @@ func (api *ApiServer) Start() {
 		api: api,
 	})
 
+	router.Handle("/healthz", &healthHandle{
+		api: api,
+	})
+
+	router.Handle("/readyz", &healthHandle{
+		api:   api,
+		ready: true,
+	})
+
+	registry := api.Metrics
+	if registry == nil {
+		registry = metrics.Default
+	}
+	api.registerMetrics(registry)
+	router.Handle("/metrics", registry)
+
+	router.Handle("/match", &matchHandle{
+		api: api,
+	})
+
 	router.Handle("/init/{username}", &initHandle{
 		api: api,
 	})
@@ -63,7 +140,5 @@ func (api *ApiServer) Start() {
 		fmt.Fprintf(w, "%s", marshalled)
 	})
 
-	log.Info().Str("addr", api.Addr).Msg("Api server starting")
-
-	http.ListenAndServe(api.Addr, router)
+	return router
 }
@@ -0,0 +1,20 @@
+package epita_api
+
+import (
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+)
+
+// emitted for every command received, successful or not
+type CommandEvent struct {
+	server.ServerEventBase
+	Response model.CommandResponse
+	// nil if the command has no parameter or it could not be read
+	Parameter any
+}
+
+func (event *CommandEvent) GetAABB() AABB {
+	// empty aabb = covers all map
+	return AABB{}
+}
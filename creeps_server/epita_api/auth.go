@@ -0,0 +1,84 @@
+package epita_api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+)
+
+// name of the header clients can use instead of Authorization: Bearer
+const TokenHeader = "X-Creeps-Token"
+
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,32}$`)
+
+// returns an error message if the username cannot be used
+func validateUsername(username string) string {
+	if !usernameRegex.MatchString(username) {
+		return "Usernames must be 1 to 32 letters, digits, '.', '_' or '-'"
+	}
+	// used for the owner of server units in observe reports
+	if username == "server" {
+		return "This username is reserved"
+	}
+	return ""
+}
+
+func newToken() string {
+	bytes := make([]byte, 24)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// the ip of the client without the port, works for ipv6 and with RealIP
+// (which removes the port)
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// the token from the Authorization or X-Creeps-Token headers or the token
+// query parameter, empty if there is none
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if token := r.Header.Get(TokenHeader); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+// returns true if the request can act as the given player
+// a request with a token must have the player's one, without a token the
+// address is checked if AllowAddrAuth is set
+func (api *ApiServer) isAuthorized(r *http.Request, player *entities.Player) bool {
+	if token := requestToken(r); token != "" {
+		return player.CheckToken(token)
+	}
+	return api.AllowAddrAuth && player.GetAddr() == remoteHost(r)
+}
+
+// returns the registered player with the given username, nil if not found
+func (api *ApiServer) findPlayer(username string) *entities.Player {
+	player, _ := api.Server.FindEntity(func(e server.IEntity) bool {
+		if p, ok := e.(*entities.Player); ok {
+			return p.GetUsername() == username
+		}
+		return false
+	}).(*entities.Player)
+	return player
+}
@@ -7,7 +7,6 @@ import (
 	"io"
 	"net/http"
 	"reflect"
-	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/heavenston/creeps_server/creeps_lib/model"
@@ -22,28 +21,44 @@ type commandHandle struct {
 }
 
 func (h *commandHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	addr := strings.Split(r.RemoteAddr, ":")[0]
-
 	login := chi.URLParam(r, "login")
 	unitIdStr := chi.URLParam(r, "unitId")
 	unitId := uid.Uid(unitIdStr)
 	strOpcode := chi.URLParam(r, "opcode")
 	opcode := model.ActionOpCode(strOpcode)
 
+	// set as soon as the player and unit are found to count misses
+	var player *entities.Player
+	var unit server.IUnit
+	var parameter any
+	misses := 0
+
 	sendError := func(code string, mess string) {
-		bytes, err := json.Marshal(model.CommandResponse{
+		if player != nil {
+			misses = player.AddMiss(unit)
+		}
+
+		response := model.CommandResponse{
 			OpCode:    opcode,
 			Login:     login,
 			UnitId:    &unitId,
 			ReportId:  nil,
 			ErrorCode: &code,
 			Error:     &mess,
+			Misses:    misses,
+		}
+		h.api.countCommand(opcode, &code)
+		h.api.Server.Events().Emit(&CommandEvent{
+			Response:  response,
+			Parameter: parameter,
 		})
+
+		bytes, err := json.Marshal(response)
 		errors.Unwrap(err)
 		w.Write(bytes)
 		log.Trace().
 			Str("login", login).Str("unitId", unitIdStr).Str("opcode", strOpcode).
-			Str("code", code).Str("mess", mess).
+			Str("code", code).Str("mess", mess).Int("misses", misses).
 			Msg("Command failed")
 	}
 
@@ -52,6 +67,13 @@ func (h *commandHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Msg("Command post")
 
 	if !opcode.IsValid() {
+		// checked first like the original server, the miss is still counted
+		// if the player is known
+		player = h.api.findPlayer(login)
+		if player != nil && (!h.api.isAuthorized(r, player) ||
+			(h.api.Match != nil && !h.api.Match.IsRunning())) {
+			player = nil
+		}
 		sendError(
 			"unrecognized",
 			fmt.Sprintf("Opcode '%s' doesn't exist", opcode),
@@ -59,20 +81,17 @@ func (h *commandHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	player, _ := h.api.Server.FindEntity(func(e server.IEntity) bool {
-		if p, ok := e.(*entities.Player); ok {
-			return p.GetUsername() == login
-		}
-		return false
-	}).(*entities.Player)
+	player = h.api.findPlayer(login)
 
-	if player == nil || player.GetAddr() != addr {
+	if player == nil || !h.api.isAuthorized(r, player) {
 		log.Trace().
 			Str("login", login).
 			Bool("found", player != nil).
-			Str("addr", addr).
+			Str("addr", remoteHost(r)).
 			Msg("Access denied")
 
+		// do not count misses for players you don't have access to
+		player = nil
 		sendError(
 			"noplayer",
 			"The login you provided does not exist or is not someone you have access to",
@@ -80,9 +99,17 @@ func (h *commandHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	unit, _ := h.api.Server.GetEntity(unitId).(server.IUnit)
+	if h.api.Match != nil && !h.api.Match.IsRunning() {
+		// not the player's fault
+		player = nil
+		sendError("notrunning", "The match is not running.")
+		return
+	}
+
+	unit, _ = h.api.Server.GetEntity(unitId).(server.IUnit)
 
 	if unit == nil || unit.GetOwner() != player.GetId() {
+		unit = nil
 		sendError(
 			"nounit",
 			"The unitId you provided did not match any of your units.",
@@ -96,7 +123,7 @@ func (h *commandHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	newAction := new(server.Action)
-	newAction.ReportId = uid.GenUid()
+	newAction.ReportId = h.api.Server.GenUid()
 	newAction.OpCode = opcode
 
 	paramType := opcode.ParameterType()
@@ -114,7 +141,8 @@ func (h *commandHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			sendError("invalidparameter", "Cannot deserialize the body")
 			return
 		}
-		newAction.Parameter = reflect.Indirect(paramValue).Interface()
+		parameter = reflect.Indirect(paramValue).Interface()
+		newAction.Parameter = parameter
 	}
 
 	err := unit.StartAction(newAction, nil)
@@ -146,8 +174,13 @@ func (h *commandHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ReportId: &newAction.ReportId,
 		Login:    login,
 		UnitId:   &unitId,
-		Misses:   0, // < TODO: Count misses (put in player struct)
+		Misses:   player.GetMisses(),
 	}
+	h.api.countCommand(opcode, nil)
+	h.api.Server.Events().Emit(&CommandEvent{
+		Response:  response,
+		Parameter: parameter,
+	})
 
 	bytes, err := json.Marshal(response)
 	errors.Unwrap(err)
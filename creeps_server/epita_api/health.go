@@ -0,0 +1,61 @@
+package epita_api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+type healthResponse struct {
+	// running, paused, starting or stalled
+	Status string `json:"status"`
+	Tick   int    `json:"tick"`
+}
+
+// see ApiServer.Start, /healthz fails only if the ticker stalled while
+// /readyz fails unless the ticker is advancing
+type healthHandle struct {
+	api   *ApiServer
+	ready bool
+}
+
+func (h *healthHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ticker := h.api.Server.Ticker()
+
+	resp := healthResponse{
+		Status: "running",
+		Tick:   ticker.GetTickNumber(),
+	}
+	switch {
+	case ticker.IsStalled():
+		resp.Status = "stalled"
+	case ticker.GetState().Paused:
+		resp.Status = "paused"
+	case !ticker.IsRunning():
+		resp.Status = "starting"
+	}
+
+	healthy := resp.Status != "stalled"
+	if h.ready {
+		healthy = resp.Status == "running"
+	}
+
+	body, err := json.Marshal(resp)
+	errors.Unwrap(err)
+
+	w.Header().Set("Content-Type", "application/json")
+	if healthy {
+		w.WriteHeader(200)
+	} else {
+		w.WriteHeader(503)
+	}
+	w.Write(body)
+
+	log.Trace().
+		Str("addr", r.RemoteAddr).
+		Bool("ready", h.ready).
+		Str("status", resp.Status).
+		Msg("Health request")
+}
@@ -3,7 +3,6 @@ package epita_api
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
 	"github.com/heavenston/creeps_server/creeps_lib/model"
@@ -15,16 +14,54 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// ticks per second given to clients when the ticker runs as fast as possible
+// (0), epita clients divide by it
+const fastTicksPerSecond = 1000
+
 type initHandle struct {
 	api *ApiServer
 }
 
 func (h *initHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	addr := strings.Split(r.RemoteAddr, ":")[0]
+	addr := remoteHost(r)
 
 	username := chi.URLParam(r, "username")
 
-	spawnPoint := h.api.Server.FindSpawnPoint(Point{}, 2, func(p Point) bool {
+	sendError := func(mess string) {
+		data, err := json.Marshal(model.InitResponse{
+			Error: &mess,
+			Login: username,
+			Tick:  h.api.Server.Ticker().GetTickNumber(),
+		})
+		if err != nil {
+			panic(err)
+		}
+		w.Write(data)
+	}
+
+	if mess := validateUsername(username); mess != "" {
+		sendError(mess)
+		log.Debug().Str("username", username).Msg("Invalid username")
+		return
+	}
+
+	if h.api.Match != nil && !h.api.Match.AcceptsPlayers() {
+		sendError("The match already started")
+		log.Debug().Str("username", username).Msg("Init after the start of the match")
+		return
+	}
+
+	h.api.initLock.Lock()
+	defer h.api.initLock.Unlock()
+
+	// dead players can init again
+	if h.api.findPlayer(username) != nil {
+		sendError("This username is already taken")
+		log.Debug().Str("username", username).Str("addr", addr).Msg("Duplicate init")
+		return
+	}
+
+	spawnPoint, hasRoom := h.api.Server.FindSpawnPoint(Point{}, 2, func(p Point) bool {
 		found := false
 		h.api.Server.ForEachEntity(func(entity server.IEntity) (shouldStop bool) {
 			eplayer, ok := entity.(*entities.Player)
@@ -37,9 +74,17 @@ func (h *initHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		})
 		return !found
 	})
+	if !hasRoom {
+		sendError("There is no room left in the world")
+		log.Warn().Str("username", username).Msg("Could not find a spawn point")
+		return
+	}
 	player := entities.NewPlayer(h.api.Server, username, addr, spawnPoint)
+	token := newToken()
+	player.SetToken(token)
 	player.SetResources(h.api.Server.GetDefaultPlayerResources())
 	townhall, household, c1, c2 := gameplay.InitPlayer(h.api.Server, player)
+	h.api.addPlayer(player)
 
 	response := model.InitResponse{}
 
@@ -48,7 +93,10 @@ func (h *initHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	c2id := c2.GetId()
 	response.Citizen2Id = &c2id
 	response.Costs = h.api.Server.GetCosts()
-	response.Setup = h.api.Server.GetSetup()
+	response.Setup = h.api.Server.GetCurrentSetup()
+	if response.Setup.TicksPerSecond <= 0 {
+		response.Setup.TicksPerSecond = fastTicksPerSecond
+	}
 	response.HouseholdCoordinates = &household
 	response.TownHallCoordinates = &townhall
 	response.Login = username
@@ -56,6 +104,7 @@ func (h *initHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	res := player.GetResources()
 	response.Resources = &res
 	response.Tick = h.api.Server.Ticker().GetTickNumber()
+	response.Token = &token
 
 	data, err := json.Marshal(response)
 	if err != nil {
@@ -0,0 +1,37 @@
+package epita_api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+type matchHandle struct {
+	api *ApiServer
+}
+
+// the state of the match and its results once finished
+func (h *matchHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.api.Match == nil {
+		w.WriteHeader(404)
+		body, err := json.Marshal(ApiErrorResponse{
+			ErrorCode: "nomatch",
+			Error:     "The game is not played as a match",
+		})
+		errors.Unwrap(err)
+		w.Write(body)
+		return
+	}
+
+	body, err := json.Marshal(h.api.Match.GetStatus())
+	errors.Unwrap(err)
+
+	w.WriteHeader(200)
+	w.Write(body)
+
+	log.Trace().
+		Str("addr", r.RemoteAddr).
+		Msg("Match request")
+}
@@ -0,0 +1,35 @@
+package epita_api
+
+import (
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
+)
+
+// creates the metrics of the api in the registry it serves, so two apis
+// (ex: the games of a manager) do not count the commands of each other
+func (api *ApiServer) registerMetrics(registry *metrics.Registry) {
+	api.commandsTotal = registry.NewCounterVec(
+		"creeps_api_commands_total",
+		"Commands received by the api, failed or not",
+		"opcode",
+	)
+	api.commandErrorsTotal = registry.NewCounterVec(
+		"creeps_api_command_errors_total",
+		"Commands that failed by error code",
+		"opcode", "error_code",
+	)
+}
+
+// counts the command, errorCode is nil if it succeeded
+func (api *ApiServer) countCommand(opcode model.ActionOpCode, errorCode *string) {
+	// the opcode comes from the url so any value would add a new label
+	label := string(opcode)
+	if !opcode.IsValid() {
+		label = "unknown"
+	}
+
+	api.commandsTotal.With(label).Inc()
+	if errorCode != nil {
+		api.commandErrorsTotal.With(label, *errorCode).Inc()
+	}
+}
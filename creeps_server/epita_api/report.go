@@ -35,6 +35,24 @@ func (h *reportHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// reports of units without a player (raiders) are not sent to anyone
+	player := h.api.findPlayer(report.GetReport().Login)
+	if player == nil || !h.api.isAuthorized(r, player) {
+		log.Trace().
+			Str("reportId", reportIdStr).
+			Str("addr", remoteHost(r)).
+			Msg("Report access denied")
+
+		body, err := json.Marshal(ApiErrorResponse{
+			ErrorCode: "forbidden",
+			Error:     "This report is not one of yours",
+		})
+		errors.Unwrap(err)
+		w.WriteHeader(403)
+		w.Write(body)
+		return
+	}
+
 	res, err := json.Marshal(report)
 	errors.Unwrap(err)
 
@@ -14,12 +14,35 @@ type statisticsHandle struct {
 }
 
 func (h *statisticsHandle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	players := h.api.copyPlayers()
+
+	running := h.api.Server.Ticker().IsRunning()
+	if h.api.Match != nil {
+		running = h.api.Match.IsRunning()
+	}
+
 	resp := model.StatisticsResponse{
 		ServerId:    h.api.Server.GetSetup().ServerId,
-		GameRunning: true,
+		GameRunning: running,
 		Tick:        h.api.Server.Ticker().GetTickNumber(),
 		Dimension:   h.api.Server.GetSetup().WorldDimension,
-		Players:     []model.Player{},
+		Players:     make([]model.Player, 0, len(players)),
+	}
+
+	for _, player := range players {
+		status := "alive"
+		if !player.IsRegistered() {
+			status = "dead"
+		}
+
+		resp.Players = append(resp.Players, model.Player{
+			Name:         player.GetUsername(),
+			Status:       status,
+			Units:        player.GetUnitCount(),
+			Buildings:    player.GetBuildingCount(),
+			Resources:    player.GetResources(),
+			Achievements: player.GetAchievements(),
+		})
 	}
 
 	body, err := json.Marshal(resp)
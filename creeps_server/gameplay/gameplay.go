@@ -27,6 +27,8 @@ func InitPlayer(
 	})
 
 	player.AddTownHall(townhall)
+	player.AddBuilding(townhall, terrain.TileTownHall)
+	player.AddBuilding(household, terrain.TileHousehold)
 
 	c1 = entities.NewCitizenUnit(srv, player.GetId())
 	c1.SetPosition(household)
@@ -21,6 +21,8 @@ type NoiseGenerator struct {
 	rand *rand.Rand
 
 	patchs []patch
+	// if not nil every tile outside of it is a TileWorldBorder
+	bounds *AABB
 }
 
 // First argument is a scale that should be applied to the simplex noise
@@ -65,7 +67,20 @@ func NewNoiseGenerator(seed int64) *NoiseGenerator {
 	return g
 }
 
+// Makes every tile generated outside of the given bounds a TileWorldBorder
+// only affects chunks generated after the call
+func (gen *NoiseGenerator) SetBounds(bounds AABB) {
+	gen.bounds = &bounds
+}
+
 func (gen *NoiseGenerator) sample(x int, y int) Tile {
+	if gen.bounds != nil && !gen.bounds.Contains(Point{X: x, Y: y}) {
+		return Tile{
+			Kind:  TileWorldBorder,
+			Value: 0,
+		}
+	}
+
 	for _, patch := range gen.patchs {
 		val := patch.sample(float64(x), float64(y))
 		if val > patch.thresh {
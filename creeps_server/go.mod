@@ -11,6 +11,7 @@ require (
 	github.com/heavenston/creeps_server/creeps_lib v0.1.0
 	github.com/ojrac/opensimplex-go v1.0.2
 	github.com/rs/zerolog v1.32.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
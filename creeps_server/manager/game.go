@@ -0,0 +1,158 @@
+package manager
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_server/achievements"
+	"github.com/heavenston/creeps_server/creeps_server/admin_api"
+	"github.com/heavenston/creeps_server/creeps_server/config"
+	"github.com/heavenston/creeps_server/creeps_server/epita_api"
+	"github.com/heavenston/creeps_server/creeps_server/generator"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/heavenston/creeps_server/creeps_server/viewer"
+)
+
+// what can be changed from the manager's config when creating a game, also
+// the body of POST /games
+type GameOptions struct {
+	// generated if empty
+	Id string `json:"id"`
+	// the id if empty
+	Name string `json:"name"`
+	// reproduces the map and spawn points, random if nil
+	Seed *int64 `json:"seed"`
+	// bounds the world to a square of the given side if positive
+	WorldSize int `json:"worldSize"`
+	// negative to run as fast as possible
+	TicksPerSecond *float64 `json:"ticksPerSecond"`
+	Enemies        *bool    `json:"enemies"`
+	Paused         bool     `json:"paused"`
+}
+
+// a game hosted by the manager's process
+type Game struct {
+	Id        string
+	Name      string
+	CreatedAt time.Time
+	Server    *server.Server
+	// nil if the game is not played as a match
+	Match *match.Match
+
+	// handlers mounted under /games/{gameId}, admin is nil if the manager
+	// has no admin secret
+	api    http.Handler
+	viewer http.Handler
+	admin  http.Handler
+}
+
+// creates the server of a game and starts its ticker
+// the options must be valid, see Manager.CreateGame
+func newGame(manager *Manager, conf config.Config, options GameOptions) *Game {
+	setup := conf.Setup
+	costs := conf.Costs
+
+	setup.ServerId = options.Id
+	if options.WorldSize > 0 {
+		setup.BoundedWorld = true
+		setup.WorldDimension = Point{X: options.WorldSize, Y: options.WorldSize}
+	}
+	if options.TicksPerSecond != nil {
+		setup.TicksPerSecond = mathutils.Max(*options.TicksPerSecond, 0)
+	}
+	if options.Enemies != nil {
+		setup.EnableEnemies = *options.Enemies
+	}
+
+	seed := time.Now().UnixMilli()
+	if options.Seed != nil {
+		seed = *options.Seed
+	}
+
+	generator := generator.NewNoiseGenerator(seed)
+	if setup.BoundedWorld {
+		generator.SetBounds(setup.WorldBounds())
+	}
+	tilemap := terrain.NewTilemap(generator)
+
+	srv := server.NewServer(&tilemap, &setup, &costs)
+	if options.Seed != nil {
+		srv.SeedUids(seed)
+		srv.SeedSpawns(seed)
+	}
+	if options.Paused {
+		srv.Ticker().Pause()
+	}
+	if setup.TrackAchievements {
+		achievements.Track(srv)
+	}
+	var gameMatch *match.Match
+	if setup.Match.Enabled {
+		// the results are served by the game's api
+		gameMatch = match.Track(srv, "")
+	}
+	srv.SetDefaultPlayerResources(conf.PlayerResources)
+
+	registry := metrics.NewRegistry()
+	srv.RegisterMetrics(registry)
+
+	game := &Game{
+		Id:        options.Id,
+		Name:      options.Name,
+		CreatedAt: time.Now(),
+		Server:    srv,
+		Match:     gameMatch,
+	}
+
+	game.api = (&epita_api.ApiServer{
+		Server:        srv,
+		AllowAddrAuth: manager.AllowAddrAuth,
+		Metrics:       registry,
+		Match:         gameMatch,
+	}).Handler()
+	gameViewer := &viewer.ViewerServer{
+		Server:             srv,
+		AllowTickerControl: manager.AllowTickerControl,
+		Match:              gameMatch,
+	}
+	gameViewer.RegisterMetrics(registry)
+	game.viewer = gameViewer.Handler()
+	if manager.AdminSecret != "" {
+		game.admin = (&admin_api.AdminServer{
+			Server: srv,
+			Secret: manager.AdminSecret,
+			Match:  gameMatch,
+		}).Handler()
+	}
+
+	tilemap.GenerateChunk(Point{X: 0, Y: 0})
+	tilemap.GenerateChunk(Point{X: 0, Y: -1})
+	tilemap.GenerateChunk(Point{X: -1, Y: 0})
+	tilemap.GenerateChunk(Point{X: -1, Y: -1})
+
+	go srv.Start()
+
+	return game
+}
+
+// counts the players that are still alive
+func (game *Game) GetPlayerCount() int {
+	return countPlayers(game.Server)
+}
+
+func countPlayers(srv *server.Server) int {
+	count := 0
+	srv.ForEachEntity(func(entity server.IEntity) (shouldStop bool) {
+		if _, ok := entity.(*entities.Player); ok {
+			count++
+		}
+		return
+	})
+	return count
+}
@@ -0,0 +1,280 @@
+package manager
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// a game as listed by the lobby, also the body of POST /register
+type gameResponse struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// false for games of other servers that registered
+	Local bool `json:"local"`
+	// relative to the lobby for local games
+	ApiUrl    string `json:"apiUrl"`
+	ViewerUrl string `json:"viewerUrl"`
+	Players   int    `json:"players"`
+	Tick      int    `json:"tick"`
+	Running   bool   `json:"running"`
+	// state of the match, empty if the game is not played as a match
+	MatchState match.State `json:"matchState,omitempty"`
+}
+
+type errorResponse struct {
+	ErrorCode string `json:"errorCode"`
+	Error     string `json:"error"`
+}
+
+func newLocalGameResponse(game *Game) gameResponse {
+	response := gameResponse{
+		Id:        game.Id,
+		Name:      game.Name,
+		Local:     true,
+		ApiUrl:    fmt.Sprintf("/games/%s/api", game.Id),
+		ViewerUrl: fmt.Sprintf("/games/%s/viewer/websocket", game.Id),
+		Players:   game.GetPlayerCount(),
+		Tick:      game.Server.Ticker().GetTickNumber(),
+		Running:   game.Server.Ticker().IsRunning(),
+	}
+	if game.Match != nil {
+		response.MatchState = game.Match.GetState()
+	}
+	return response
+}
+
+func newRemoteGameResponse(game *RemoteGame) gameResponse {
+	return gameResponse{
+		Id:         game.Id,
+		Name:       game.Name,
+		ApiUrl:     game.ApiUrl,
+		ViewerUrl:  game.ViewerUrl,
+		Players:    game.Players,
+		Tick:       game.Tick,
+		Running:    game.Running,
+		MatchState: game.MatchState,
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "Internal Server Error: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, mess string) {
+	writeJson(w, status, errorResponse{
+		ErrorCode: code,
+		Error:     mess,
+	})
+}
+
+// decodes the json body into target, writes an error and returns false if it
+// cannot, an empty body is accepted if allowEmpty is set
+func readBody(w http.ResponseWriter, r *http.Request, target any, allowEmpty bool) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, 400, "invalidbody", "Cannot read the body")
+		return false
+	}
+	if allowEmpty && len(strings.TrimSpace(string(body))) == 0 {
+		return true
+	}
+	err = json.Unmarshal(body, target)
+	if err != nil {
+		writeError(w, 400, "invalidbody", fmt.Sprintf("Cannot deserialize the body: %s", err))
+		return false
+	}
+	return true
+}
+
+// refuses requests without the secret if the manager has one
+func (manager *Manager) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if manager.Secret != "" &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(manager.Secret)) != 1 {
+			log.Warn().
+				Str("addr", r.RemoteAddr).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Msg("LOBBY: Unauthorized request")
+			writeError(w, 401, "unauthorized", "Missing or invalid secret")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (manager *Manager) listGames(w http.ResponseWriter, r *http.Request) {
+	games := make([]gameResponse, 0)
+	for _, game := range manager.GetGames() {
+		games = append(games, newLocalGameResponse(game))
+	}
+	for _, game := range manager.GetRemoteGames() {
+		games = append(games, newRemoteGameResponse(game))
+	}
+	writeJson(w, 200, games)
+}
+
+func (manager *Manager) getGame(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "gameId")
+	if game := manager.GetGame(id); game != nil {
+		writeJson(w, 200, newLocalGameResponse(game))
+		return
+	}
+	if game := manager.GetRemoteGame(id); game != nil {
+		writeJson(w, 200, newRemoteGameResponse(game))
+		return
+	}
+	writeError(w, 404, "nogame", "There is no game with this id")
+}
+
+func (manager *Manager) createGame(w http.ResponseWriter, r *http.Request) {
+	var options GameOptions
+	if !readBody(w, r, &options, true) {
+		return
+	}
+
+	game, err := manager.CreateGame(options)
+	if errors.Is(err, ErrInvalidGameId) {
+		writeError(w, 400, "invalidid", err.Error())
+		return
+	}
+	if errors.Is(err, ErrGameExists) {
+		writeError(w, 409, "gameexists", err.Error())
+		return
+	}
+
+	writeJson(w, 201, newLocalGameResponse(game))
+}
+
+func (manager *Manager) deleteGame(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "gameId")
+	if game := manager.GetGame(id); game != nil {
+		response := newLocalGameResponse(game)
+		if manager.DeleteGame(id) {
+			writeJson(w, 200, response)
+			return
+		}
+	}
+	if game := manager.GetRemoteGame(id); game != nil {
+		response := newRemoteGameResponse(game)
+		if manager.ForgetRemoteGame(id) {
+			writeJson(w, 200, response)
+			return
+		}
+	}
+	writeError(w, 404, "nogame", "There is no game with this id")
+}
+
+func (manager *Manager) registerGame(w http.ResponseWriter, r *http.Request) {
+	var body gameResponse
+	if !readBody(w, r, &body, false) {
+		return
+	}
+	if body.ApiUrl == "" {
+		writeError(w, 400, "invalidbody", "The api url is required")
+		return
+	}
+	if body.Name == "" {
+		body.Name = body.Id
+	}
+
+	game := RemoteGame{
+		Id:         body.Id,
+		Name:       body.Name,
+		ApiUrl:     body.ApiUrl,
+		ViewerUrl:  body.ViewerUrl,
+		Players:    body.Players,
+		Tick:       body.Tick,
+		Running:    body.Running,
+		MatchState: body.MatchState,
+	}
+	err := manager.RegisterRemoteGame(game)
+	if errors.Is(err, ErrInvalidGameId) {
+		writeError(w, 400, "invalidid", err.Error())
+		return
+	}
+	if errors.Is(err, ErrGameExists) {
+		writeError(w, 409, "gameexists", err.Error())
+		return
+	}
+
+	writeJson(w, 200, newRemoteGameResponse(&game))
+}
+
+// serves the handler returned by f for the game of the url
+func (manager *Manager) gameHandler(f func(game *Game) http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		game := manager.GetGame(chi.URLParam(r, "gameId"))
+		if game == nil {
+			writeError(w, 404, "nogame", "There is no game with this id")
+			return
+		}
+		handler := f(game)
+		if handler == nil {
+			writeError(w, 404, "notfound", "Endpoint does not exist")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Serves the lobby api on Addr
+//   - GET /games lists local and remote games
+//   - POST /games creates a game from the GameOptions of the body
+//   - GET and DELETE /games/{gameId}
+//   - POST /register adds or refreshes a remote game, see Registration
+//   - /games/{gameId}/api/..., /games/{gameId}/viewer/websocket and
+//     /games/{gameId}/admin/... are the apis of local games
+//
+// creating and deleting games and registering need the secret if set
+func (manager *Manager) Start() {
+	router := chi.NewRouter()
+	router.Use(middleware.RealIP)
+	router.Use(middleware.Recoverer)
+
+	router.Get("/games", manager.listGames)
+	router.With(manager.authenticate).Post("/games", manager.createGame)
+	router.Get("/games/{gameId}", manager.getGame)
+	router.With(manager.authenticate).Delete("/games/{gameId}", manager.deleteGame)
+	router.With(manager.authenticate).Post("/register", manager.registerGame)
+
+	router.Mount("/games/{gameId}/api", manager.gameHandler(func(game *Game) http.Handler {
+		return game.api
+	}))
+	router.Mount("/games/{gameId}/viewer", manager.gameHandler(func(game *Game) http.Handler {
+		return game.viewer
+	}))
+	router.Mount("/games/{gameId}/admin", manager.gameHandler(func(game *Game) http.Handler {
+		return game.admin
+	}))
+
+	router.Handle("/metrics", metrics.Default)
+
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, 404, "notfound", "Lobby endpoint does not exist")
+	})
+
+	log.Info().Str("addr", manager.Addr).Msg("Lobby server starting")
+
+	http.ListenAndServe(manager.Addr, router)
+}
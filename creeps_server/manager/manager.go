@@ -0,0 +1,267 @@
+package manager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heavenston/creeps_server/creeps_server/config"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// interval at which game servers register again with their master
+const RegisterInterval = 10 * time.Second
+
+// games registered by other servers are forgotten if they did not register
+// again during this time
+const RemoteGameTimeout = 3 * RegisterInterval
+
+var ErrInvalidGameId = errors.New("game ids must be 1 to 32 letters, digits, '.', '_' or '-'")
+var ErrGameExists = errors.New("a game with this id already exists")
+
+var gameIdRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,32}$`)
+
+// a game hosted by another server that registered with the manager, see
+// Registration
+type RemoteGame struct {
+	Id        string
+	Name      string
+	ApiUrl    string
+	ViewerUrl string
+	Players   int
+	Tick      int
+	Running   bool
+	// empty if the game is not played as a match
+	MatchState match.State
+	// last time it registered
+	LastSeen time.Time
+}
+
+// Hosts several games in the same process and lists them along with the ones
+// of other servers that registered, see Start for the lobby api
+type Manager struct {
+	// games are created from this config
+	config config.Config
+
+	Addr string
+	// needed to create and delete games and to register a remote one, not
+	// checked if empty
+	Secret string
+	// see epita_api.ApiServer.AllowAddrAuth
+	AllowAddrAuth bool
+	// see viewer.ViewerServer.AllowTickerControl
+	AllowTickerControl bool
+	// the admin api of games is only served if set, see admin_api.AdminServer
+	AdminSecret string
+
+	lock        sync.RWMutex
+	games       map[string]*Game
+	remoteGames map[string]*RemoteGame
+	// ids of the games being created by CreateGame, reserved so the lock is
+	// not held while their world is generated
+	creating map[string]struct{}
+}
+
+func NewManager(conf config.Config) *Manager {
+	return &Manager{
+		config:      conf,
+		games:       make(map[string]*Game),
+		remoteGames: make(map[string]*RemoteGame),
+		creating:    make(map[string]struct{}),
+	}
+}
+
+// creates a new game and starts it
+// returns ErrInvalidGameId or ErrGameExists if the id cannot be used
+func (manager *Manager) CreateGame(options GameOptions) (*Game, error) {
+	manager.lock.Lock()
+	if options.Id == "" {
+		for options.Id == "" || manager.idTaken(options.Id) {
+			options.Id = newGameId()
+		}
+	}
+	if !gameIdRegex.MatchString(options.Id) {
+		manager.lock.Unlock()
+		return nil, ErrInvalidGameId
+	}
+	if manager.idTaken(options.Id) {
+		manager.lock.Unlock()
+		return nil, ErrGameExists
+	}
+	manager.creating[options.Id] = struct{}{}
+	manager.lock.Unlock()
+
+	if options.Name == "" {
+		options.Name = options.Id
+	}
+
+	// outside of the lock as it generates the chunks around the spawns
+	game := newGame(manager, manager.config, options)
+
+	manager.lock.Lock()
+	delete(manager.creating, game.Id)
+	manager.games[game.Id] = game
+	manager.lock.Unlock()
+
+	log.Info().
+		Str("game_id", game.Id).
+		Str("name", game.Name).
+		Msg("Game created")
+
+	return game, nil
+}
+
+// returns nil if there is no local game with this id
+func (manager *Manager) GetGame(id string) *Game {
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+	return manager.games[id]
+}
+
+// returns the local games ordered by creation
+func (manager *Manager) GetGames() []*Game {
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	games := make([]*Game, 0, len(manager.games))
+	for _, game := range manager.games {
+		games = append(games, game)
+	}
+	slices.SortFunc(games, func(a, b *Game) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+	return games
+}
+
+// stops the game and removes it
+// returns false if there is no local game with this id
+func (manager *Manager) DeleteGame(id string) bool {
+	manager.lock.Lock()
+	game := manager.games[id]
+	delete(manager.games, id)
+	manager.lock.Unlock()
+
+	if game == nil {
+		return false
+	}
+
+	// outside of the lock as it waits for the end of the current tick
+	game.Server.Stop()
+
+	log.Info().Str("game_id", id).Msg("Game deleted")
+	return true
+}
+
+// adds or refreshes a game of another server
+// returns ErrInvalidGameId or ErrGameExists if the id is used by a local game
+func (manager *Manager) RegisterRemoteGame(game RemoteGame) error {
+	if !gameIdRegex.MatchString(game.Id) {
+		return ErrInvalidGameId
+	}
+
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	if _, exists := manager.games[game.Id]; exists {
+		return ErrGameExists
+	}
+	if _, exists := manager.creating[game.Id]; exists {
+		return ErrGameExists
+	}
+	if _, exists := manager.remoteGames[game.Id]; !exists {
+		log.Info().
+			Str("game_id", game.Id).
+			Str("api_url", game.ApiUrl).
+			Msg("Remote game registered")
+	}
+
+	game.LastSeen = time.Now()
+	manager.remoteGames[game.Id] = &game
+	return nil
+}
+
+// returns nil if there is no remote game with this id or it timed out
+func (manager *Manager) GetRemoteGame(id string) *RemoteGame {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.forgetTimedOut()
+	return manager.remoteGames[id]
+}
+
+// returns the remote games that did not time out ordered by id
+func (manager *Manager) GetRemoteGames() []*RemoteGame {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.forgetTimedOut()
+	games := make([]*RemoteGame, 0, len(manager.remoteGames))
+	for _, game := range manager.remoteGames {
+		games = append(games, game)
+	}
+	slices.SortFunc(games, func(a, b *RemoteGame) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return games
+}
+
+// returns false if there is no remote game with this id
+func (manager *Manager) ForgetRemoteGame(id string) bool {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	_, exists := manager.remoteGames[id]
+	delete(manager.remoteGames, id)
+	return exists
+}
+
+// must be called with the lock
+func (manager *Manager) forgetTimedOut() {
+	for id, game := range manager.remoteGames {
+		if time.Since(game.LastSeen) > RemoteGameTimeout {
+			delete(manager.remoteGames, id)
+			log.Info().Str("game_id", id).Msg("Remote game timed out")
+		}
+	}
+}
+
+// must be called with the lock
+func (manager *Manager) idTaken(id string) bool {
+	_, local := manager.games[id]
+	_, remote := manager.remoteGames[id]
+	_, creating := manager.creating[id]
+	return local || remote || creating
+}
+
+// Adds to the registry the amount of local and remote games
+// must be called only once per registry
+func (manager *Manager) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewGaugeVecFunc(
+		"creeps_games",
+		"Games listed by the lobby by location (local or remote)",
+		[]string{"location"},
+		func(set metrics.SetFunc) {
+			set(float64(len(manager.GetGames())), "local")
+			set(float64(len(manager.GetRemoteGames())), "remote")
+		},
+	)
+}
+
+func newGameId() string {
+	bytes := make([]byte, 4)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/rs/zerolog/log"
+)
+
+// Registers a game hosted by a single game server with a master so its lobby
+// lists it, see Start
+type Registration struct {
+	// base url of the master's lobby (ex: http://localhost:1660)
+	MasterUrl string
+	// secret of the master, can be empty
+	Secret string
+
+	Id        string
+	Name      string
+	ApiUrl    string
+	ViewerUrl string
+
+	Server *server.Server
+	// nil if the game is not played as a match
+	Match *match.Match
+}
+
+// Registers the game every RegisterInterval until the server is stopped
+func (registration *Registration) Start() {
+	client := &http.Client{
+		Timeout: RegisterInterval,
+	}
+
+	// only logs the first time and when it starts or stops failing
+	first := true
+	failing := false
+	for !registration.Server.Ticker().IsStopped() {
+		err := registration.register(client)
+		if err != nil && !failing {
+			log.Warn().Err(err).
+				Str("master_url", registration.MasterUrl).
+				Msg("Could not register with the master, retrying")
+		}
+		if err == nil && (first || failing) {
+			log.Info().
+				Str("master_url", registration.MasterUrl).
+				Str("game_id", registration.Id).
+				Msg("Registered with the master")
+		}
+		first = false
+		failing = err != nil
+
+		time.Sleep(RegisterInterval)
+	}
+}
+
+func (registration *Registration) register(client *http.Client) error {
+	srv := registration.Server
+	game := gameResponse{
+		Id:        registration.Id,
+		Name:      registration.Name,
+		ApiUrl:    registration.ApiUrl,
+		ViewerUrl: registration.ViewerUrl,
+		Players:   countPlayers(srv),
+		Tick:      srv.Ticker().GetTickNumber(),
+		Running:   srv.Ticker().IsRunning(),
+	}
+	if registration.Match != nil {
+		game.MatchState = registration.Match.GetState()
+	}
+	body, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(registration.MasterUrl, "/") + "/register"
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if registration.Secret != "" {
+		request.Header.Set("Authorization", "Bearer "+registration.Secret)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		var body errorResponse
+		json.NewDecoder(response.Body).Decode(&body)
+		return fmt.Errorf("master answered %d: %s", response.StatusCode, body.Error)
+	}
+	return nil
+}
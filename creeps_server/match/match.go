@@ -0,0 +1,398 @@
+package match
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/rs/zerolog/log"
+)
+
+type State string
+
+const (
+	// players can join, the ticker is paused
+	StateWaiting State = "waiting"
+	// enough players joined, they can still join until the end of the
+	// countdown
+	StateCountdown State = "countdown"
+	// the ticker runs and players cannot join anymore
+	StateRunning State = "running"
+	// the ticker is paused and the results are known
+	StateFinished State = "finished"
+)
+
+// why a match finished, see Results
+const (
+	ReasonLastPlayerAlive = "lastPlayerAlive"
+	ReasonTickLimit       = "tickLimit"
+	ReasonScoreThreshold  = "scoreThreshold"
+	// finished with Finish, usually by an admin
+	ReasonStopped = "stopped"
+)
+
+// how often the players are counted while waiting or counting down
+const lobbyCheckInterval = 200 * time.Millisecond
+
+var ErrAlreadyStarted = errors.New("the match already started")
+var ErrAlreadyFinished = errors.New("the match already finished")
+var ErrNotRunning = errors.New("the match is not running")
+
+// a player that was alive when the match started or joined after
+type participant struct {
+	player *entities.Player
+	// last score computed while it was alive
+	score float64
+	// -1 while alive
+	diedAtTick int
+}
+
+// Drives the lifecycle of a match on a server, see Track
+type Match struct {
+	server *server.Server
+	setup  model.MatchSetup
+	// see Track
+	resultsPath string
+
+	// guards everything below
+	lock  sync.Mutex
+	state State
+	// end of the countdown, only meaningful in StateCountdown
+	countdownEnd  time.Time
+	startedAtTick int
+	participants  map[uid.Uid]*participant
+	// set when finished
+	results *Results
+}
+
+// Starts a match on the server following setup.Match, the ticker is paused
+// until the match starts and paused again when it finishes
+// the results are written to resultsPath when it finishes, if not empty
+// should only be called once per server, before its ticker is started
+func Track(srv *server.Server, resultsPath string) *Match {
+	match := &Match{
+		server:       srv,
+		setup:        srv.GetSetup().Match,
+		resultsPath:  resultsPath,
+		state:        StateWaiting,
+		participants: make(map[uid.Uid]*participant),
+	}
+	if match.setup.MinPlayers < 1 {
+		match.setup.MinPlayers = 1
+	}
+
+	srv.Ticker().Pause()
+	srv.Ticker().AddTickFunc(match.tick)
+
+	go match.watchLobby()
+
+	log.Info().
+		Int("min_players", match.setup.MinPlayers).
+		Msg("Match waiting for players")
+
+	return match
+}
+
+func (match *Match) GetState() State {
+	match.lock.Lock()
+	defer match.lock.Unlock()
+	return match.state
+}
+
+func (match *Match) IsRunning() bool {
+	return match.GetState() == StateRunning
+}
+
+// true while waiting for players or counting down
+func (match *Match) AcceptsPlayers() bool {
+	state := match.GetState()
+	return state == StateWaiting || state == StateCountdown
+}
+
+// returns nil until the match is finished
+func (match *Match) GetResults() *Results {
+	match.lock.Lock()
+	defer match.lock.Unlock()
+	return match.results
+}
+
+// Starts the match now, skipping the wait for players and the countdown
+// returns ErrAlreadyStarted or ErrAlreadyFinished if it cannot
+func (match *Match) Start() error {
+	match.lock.Lock()
+	defer match.lock.Unlock()
+
+	switch match.state {
+	case StateRunning:
+		return ErrAlreadyStarted
+	case StateFinished:
+		return ErrAlreadyFinished
+	}
+	match.start()
+	return nil
+}
+
+// Finishes the match now (with ReasonStopped), waiting for the current tick
+// to end
+// returns ErrAlreadyFinished if it is already finished
+// must not be called from the ticker's goroutine (it would deadlock)
+func (match *Match) Finish() error {
+	match.server.Ticker().PauseAndWait()
+
+	match.lock.Lock()
+	defer match.lock.Unlock()
+
+	if match.state == StateFinished {
+		return ErrAlreadyFinished
+	}
+	match.updateParticipants()
+	match.finish(ReasonStopped)
+	return nil
+}
+
+// Pauses the ticker, which the match controls unless it is running
+// returns ErrNotRunning if it is not
+func (match *Match) Pause() error {
+	return match.controlTicker(func(ticker *server.Ticker) {
+		ticker.Pause()
+	})
+}
+
+// see Pause
+func (match *Match) Resume() error {
+	return match.controlTicker(func(ticker *server.Ticker) {
+		ticker.Resume()
+	})
+}
+
+// see Pause and Ticker.Step
+func (match *Match) Step(ticks int) error {
+	return match.controlTicker(func(ticker *server.Ticker) {
+		ticker.Step(ticks)
+	})
+}
+
+// calls f with the ticker if the match is running, with the lock so it cannot
+// start or finish in between
+func (match *Match) controlTicker(f func(ticker *server.Ticker)) error {
+	match.lock.Lock()
+	defer match.lock.Unlock()
+
+	if match.state != StateRunning {
+		return ErrNotRunning
+	}
+	f(match.server.Ticker())
+	return nil
+}
+
+// moves from waiting to countdown to running depending on the amount of
+// players, until the match starts
+func (match *Match) watchLobby() {
+	ticker := time.NewTicker(lobbyCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if match.server.Ticker().IsStopped() || !match.checkLobby() {
+			return
+		}
+	}
+}
+
+// returns false once the match started
+func (match *Match) checkLobby() bool {
+	players := len(alivePlayers(match.server))
+
+	match.lock.Lock()
+	defer match.lock.Unlock()
+
+	switch match.state {
+	case StateWaiting:
+		if players < match.setup.MinPlayers {
+			return true
+		}
+		match.countdownEnd = time.Now().Add(time.Duration(match.setup.CountdownSeconds) * time.Second)
+		match.setState(StateCountdown)
+		log.Info().
+			Int("players", players).
+			Time("starts_at", match.countdownEnd).
+			Msg("Match countdown started")
+	case StateCountdown:
+		// players can be kicked by an admin
+		if players < match.setup.MinPlayers {
+			match.setState(StateWaiting)
+			log.Info().Int("players", players).Msg("Match countdown cancelled")
+			return true
+		}
+		if time.Now().Before(match.countdownEnd) {
+			return true
+		}
+		match.start()
+	}
+	return match.state == StateWaiting || match.state == StateCountdown
+}
+
+// must be called with the lock
+func (match *Match) start() {
+	match.startedAtTick = match.server.Ticker().GetTickNumber()
+	for _, player := range alivePlayers(match.server) {
+		match.addParticipant(player)
+	}
+	match.setState(StateRunning)
+	match.server.Ticker().Resume()
+
+	log.Info().
+		Int("players", len(match.participants)).
+		Int("tick", match.startedAtTick).
+		Msg("Match started")
+}
+
+// must be called with the lock
+func (match *Match) addParticipant(player *entities.Player) *participant {
+	p := match.participants[player.GetId()]
+	if p == nil {
+		p = &participant{
+			player:     player,
+			diedAtTick: -1,
+		}
+		match.participants[player.GetId()] = p
+	}
+	return p
+}
+
+// called each tick, finishes the match if an end condition is met
+func (match *Match) tick() {
+	match.lock.Lock()
+	defer match.lock.Unlock()
+
+	if match.state != StateRunning {
+		return
+	}
+
+	match.updateParticipants()
+
+	if reason := match.endReason(); reason != "" {
+		match.finish(reason)
+	}
+}
+
+// updates the scores of alive participants and the death tick of the others
+// must be called with the lock
+func (match *Match) updateParticipants() {
+	tick := match.server.Ticker().GetTickNumber()
+
+	// players that joined after the start (restored or spawned by an admin)
+	for _, player := range alivePlayers(match.server) {
+		match.addParticipant(player)
+	}
+
+	for _, p := range match.participants {
+		if p.diedAtTick >= 0 {
+			continue
+		}
+		if !p.player.IsRegistered() {
+			p.diedAtTick = tick
+			continue
+		}
+		p.score = match.score(p.player)
+	}
+}
+
+// returns the reason why the match should finish now, empty if it should not
+// must be called with the lock
+func (match *Match) endReason() string {
+	setup := match.setup
+
+	if setup.LastPlayerAlive && len(match.participants) > 0 {
+		alive := 0
+		for _, p := range match.participants {
+			if p.diedAtTick < 0 {
+				alive++
+			}
+		}
+		// a single player plays until it dies
+		if alive == 0 || (alive == 1 && len(match.participants) > 1) {
+			return ReasonLastPlayerAlive
+		}
+	}
+
+	if setup.TickLimit > 0 &&
+		match.server.Ticker().GetTickNumber()-match.startedAtTick >= setup.TickLimit {
+		return ReasonTickLimit
+	}
+
+	if setup.ScoreThreshold > 0 {
+		for _, p := range match.participants {
+			if p.diedAtTick < 0 && p.score >= setup.ScoreThreshold {
+				return ReasonScoreThreshold
+			}
+		}
+	}
+
+	return ""
+}
+
+// must be called with the lock
+func (match *Match) finish(reason string) {
+	match.results = match.computeResults(reason)
+	match.setState(StateFinished)
+	// does not wait so it can be called from the ticker's goroutine
+	match.server.Ticker().Pause()
+
+	event := log.Info().
+		Str("reason", reason).
+		Int("tick", match.results.FinishedAtTick)
+	if match.results.Winner != nil {
+		event = event.Str("winner", *match.results.Winner)
+	}
+	event.Msg("Match finished")
+
+	if match.resultsPath != "" {
+		// the lock is held and this is usually the ticker's goroutine, the
+		// results are never modified once computed
+		go writeResults(match.results, match.resultsPath)
+	}
+}
+
+func writeResults(results *Results, path string) {
+	err := results.WriteFile(path)
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Could not write the match results")
+	} else {
+		log.Info().Str("path", path).Msg("Match results written")
+	}
+}
+
+// must be called with the lock
+func (match *Match) setState(state State) {
+	match.state = state
+	match.server.Events().Emit(&StateEvent{
+		Status: match.status(),
+	})
+}
+
+// score of a player following setup.Score
+func (match *Match) score(player *entities.Player) float64 {
+	weights := match.setup.Score
+	res := player.GetResources()
+	resources := res.Rock + res.Wood + res.Food + res.Oil + res.Copper + res.WoodPlank
+
+	return weights.PerUnit*float64(player.GetUnitCount()) +
+		weights.PerBuilding*float64(player.GetBuildingCount()) +
+		weights.PerAchievement*float64(len(player.GetAchievements())) +
+		weights.PerResource*float64(resources)
+}
+
+func alivePlayers(srv *server.Server) []*entities.Player {
+	players := make([]*entities.Player, 0)
+	srv.ForEachEntity(func(entity server.IEntity) (shouldStop bool) {
+		if player, ok := entity.(*entities.Player); ok {
+			players = append(players, player)
+		}
+		return
+	})
+	return players
+}
@@ -0,0 +1,211 @@
+package match
+
+import (
+	"slices"
+	"testing"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+// a participant of a test, alive if diedAtTick is -1
+type testParticipant struct {
+	username   string
+	score      float64
+	diedAtTick int
+}
+
+// the players are not registered, the participants are used as is
+func newTestMatch(srv *server.Server, setup model.MatchSetup, participants []testParticipant) *Match {
+	match := &Match{
+		server:       srv,
+		setup:        setup,
+		state:        StateRunning,
+		participants: make(map[uid.Uid]*participant),
+	}
+	for _, p := range participants {
+		player := entities.NewPlayer(srv, p.username, "", Point{})
+		match.participants[player.GetId()] = &participant{
+			player:     player,
+			score:      p.score,
+			diedAtTick: p.diedAtTick,
+		}
+	}
+	return match
+}
+
+func TestRankings(t *testing.T) {
+	tests := []struct {
+		name         string
+		participants []testParticipant
+		// usernames, best first
+		expected []string
+	}{
+		{
+			name: "alive by score",
+			participants: []testParticipant{
+				{username: "low", score: 1, diedAtTick: -1},
+				{username: "high", score: 10, diedAtTick: -1},
+				{username: "mid", score: 5, diedAtTick: -1},
+			},
+			expected: []string{"high", "mid", "low"},
+		},
+		{
+			name: "alive before dead",
+			participants: []testParticipant{
+				{username: "dead", score: 100, diedAtTick: 50},
+				{username: "alive", score: 0, diedAtTick: -1},
+			},
+			expected: []string{"alive", "dead"},
+		},
+		{
+			name: "dead by survival then score",
+			participants: []testParticipant{
+				{username: "early", score: 100, diedAtTick: 10},
+				{username: "late", score: 1, diedAtTick: 90},
+				{username: "lateBetter", score: 2, diedAtTick: 90},
+			},
+			expected: []string{"lateBetter", "late", "early"},
+		},
+		{
+			name: "ties by username",
+			participants: []testParticipant{
+				{username: "bob", score: 3, diedAtTick: -1},
+				{username: "alice", score: 3, diedAtTick: -1},
+			},
+			expected: []string{"alice", "bob"},
+		},
+	}
+
+	srv := servertest.NewServer(0)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match := newTestMatch(srv, model.MatchSetup{}, test.participants)
+			results := match.computeResults(ReasonStopped)
+
+			usernames := make([]string, 0, len(results.Rankings))
+			for i, ranking := range results.Rankings {
+				if ranking.Rank != i+1 {
+					t.Errorf("%s: expected rank %d, got %d", ranking.Username, i+1, ranking.Rank)
+				}
+				usernames = append(usernames, ranking.Username)
+			}
+			if !slices.Equal(usernames, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, usernames)
+			}
+			if results.Winner == nil || *results.Winner != test.expected[0] {
+				t.Errorf("expected %s to win, got %v", test.expected[0], results.Winner)
+			}
+		})
+	}
+}
+
+func TestEndReason(t *testing.T) {
+	alive := func(username string, score float64) testParticipant {
+		return testParticipant{username: username, score: score, diedAtTick: -1}
+	}
+	dead := func(username string) testParticipant {
+		return testParticipant{username: username, diedAtTick: 5}
+	}
+
+	tests := []struct {
+		name         string
+		setup        model.MatchSetup
+		participants []testParticipant
+		// ticks since the start of the match
+		elapsed  int
+		expected string
+	}{
+		{
+			name:         "nothing enabled",
+			participants: []testParticipant{alive("a", 100), dead("b")},
+			elapsed:      1000,
+			expected:     "",
+		},
+		{
+			name:         "last player alive",
+			setup:        model.MatchSetup{LastPlayerAlive: true},
+			participants: []testParticipant{alive("a", 0), dead("b"), dead("c")},
+			expected:     ReasonLastPlayerAlive,
+		},
+		{
+			name:         "two players alive",
+			setup:        model.MatchSetup{LastPlayerAlive: true},
+			participants: []testParticipant{alive("a", 0), alive("b", 0), dead("c")},
+			expected:     "",
+		},
+		{
+			name:         "single player plays until it dies",
+			setup:        model.MatchSetup{LastPlayerAlive: true},
+			participants: []testParticipant{alive("a", 0)},
+			expected:     "",
+		},
+		{
+			name:         "single player died",
+			setup:        model.MatchSetup{LastPlayerAlive: true},
+			participants: []testParticipant{dead("a")},
+			expected:     ReasonLastPlayerAlive,
+		},
+		{
+			name:         "no participants",
+			setup:        model.MatchSetup{LastPlayerAlive: true},
+			participants: []testParticipant{},
+			expected:     "",
+		},
+		{
+			name:         "before the tick limit",
+			setup:        model.MatchSetup{TickLimit: 100},
+			participants: []testParticipant{alive("a", 0), alive("b", 0)},
+			elapsed:      99,
+			expected:     "",
+		},
+		{
+			name:         "tick limit",
+			setup:        model.MatchSetup{TickLimit: 100},
+			participants: []testParticipant{alive("a", 0), alive("b", 0)},
+			elapsed:      100,
+			expected:     ReasonTickLimit,
+		},
+		{
+			name:         "score threshold",
+			setup:        model.MatchSetup{ScoreThreshold: 50},
+			participants: []testParticipant{alive("a", 10), alive("b", 50)},
+			expected:     ReasonScoreThreshold,
+		},
+		{
+			name:         "score threshold ignores the dead",
+			setup:        model.MatchSetup{ScoreThreshold: 50},
+			participants: []testParticipant{alive("a", 10), {username: "b", score: 80, diedAtTick: 5}},
+			expected:     "",
+		},
+		{
+			name: "last player alive first",
+			setup: model.MatchSetup{
+				LastPlayerAlive: true,
+				TickLimit:       10,
+				ScoreThreshold:  50,
+			},
+			participants: []testParticipant{alive("a", 60), dead("b")},
+			elapsed:      10,
+			expected:     ReasonLastPlayerAlive,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := servertest.NewServer(0)
+			match := newTestMatch(srv, test.setup, test.participants)
+			match.startedAtTick = 20
+			srv.Ticker().SetTickNumber(match.startedAtTick + test.elapsed)
+
+			reason := match.endReason()
+			if reason != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, reason)
+			}
+		})
+	}
+}
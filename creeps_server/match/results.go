@@ -0,0 +1,115 @@
+package match
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+)
+
+// Final results of a match, produced when it finishes
+type Results struct {
+	ServerId string `json:"serverId"`
+	// see the Reason constants
+	Reason         string    `json:"reason"`
+	StartedAtTick  int       `json:"startedAtTick"`
+	FinishedAtTick int       `json:"finishedAtTick"`
+	FinishedAt     time.Time `json:"finishedAt"`
+	// username of the first of the rankings, nil if no one played
+	Winner   *string   `json:"winner"`
+	Rankings []Ranking `json:"rankings"`
+}
+
+// a player in the results, alive players are ranked first by score, then
+// dead players by how long they survived and then by score
+type Ranking struct {
+	// starts at 1
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	// the last score computed while the player was alive
+	Score float64 `json:"score"`
+	Alive bool    `json:"alive"`
+	// nil if alive
+	DiedAtTick   *int            `json:"diedAtTick"`
+	Units        int             `json:"units"`
+	Buildings    int             `json:"buildings"`
+	Resources    model.Resources `json:"resources"`
+	Achievements []string        `json:"achievements"`
+}
+
+// must be called with the lock
+func (match *Match) computeResults(reason string) *Results {
+	participants := make([]*participant, 0, len(match.participants))
+	for _, p := range match.participants {
+		participants = append(participants, p)
+	}
+	slices.SortFunc(participants, compareParticipants)
+
+	results := &Results{
+		ServerId:       match.server.GetSetup().ServerId,
+		Reason:         reason,
+		StartedAtTick:  match.startedAtTick,
+		FinishedAtTick: match.server.Ticker().GetTickNumber(),
+		FinishedAt:     time.Now(),
+		Rankings:       make([]Ranking, 0, len(participants)),
+	}
+
+	for i, p := range participants {
+		ranking := Ranking{
+			Rank:         i + 1,
+			Username:     p.player.GetUsername(),
+			Score:        p.score,
+			Alive:        p.diedAtTick < 0,
+			Units:        p.player.GetUnitCount(),
+			Buildings:    p.player.GetBuildingCount(),
+			Resources:    p.player.GetResources(),
+			Achievements: p.player.GetAchievements(),
+		}
+		if !ranking.Alive {
+			diedAt := p.diedAtTick
+			ranking.DiedAtTick = &diedAt
+		}
+		results.Rankings = append(results.Rankings, ranking)
+	}
+
+	if len(results.Rankings) > 0 {
+		winner := results.Rankings[0].Username
+		results.Winner = &winner
+	}
+
+	return results
+}
+
+// best first
+func compareParticipants(a, b *participant) int {
+	aAlive, bAlive := a.diedAtTick < 0, b.diedAtTick < 0
+	if aAlive != bAlive {
+		if aAlive {
+			return -1
+		}
+		return 1
+	}
+	// survived longer
+	if a.diedAtTick != b.diedAtTick {
+		return b.diedAtTick - a.diedAtTick
+	}
+	if a.score != b.score {
+		if a.score > b.score {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.player.GetUsername(), b.player.GetUsername())
+}
+
+// writes the results as indented json, the file is replaced if it exists
+func (results *Results) WriteFile(path string) error {
+	bytes, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(bytes, '\n'), 0644)
+}
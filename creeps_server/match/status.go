@@ -0,0 +1,58 @@
+package match
+
+import (
+	"time"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+)
+
+// State of a match as sent to the clients, see GetStatus
+type Status struct {
+	State      State `json:"state"`
+	MinPlayers int   `json:"minPlayers"`
+	// nil unless counting down
+	StartsAt *time.Time `json:"startsAt"`
+	// nil until started
+	StartedAtTick *int `json:"startedAtTick"`
+	// 0 if there is no limit
+	TickLimit int `json:"tickLimit"`
+	// nil until finished
+	Results *Results `json:"results"`
+}
+
+// emitted every time the state of a match changes
+type StateEvent struct {
+	server.ServerEventBase
+	Status Status
+}
+
+// covers the whole map
+func (event *StateEvent) GetAABB() AABB {
+	return AABB{}
+}
+
+func (match *Match) GetStatus() Status {
+	match.lock.Lock()
+	defer match.lock.Unlock()
+	return match.status()
+}
+
+// must be called with the lock
+func (match *Match) status() Status {
+	status := Status{
+		State:      match.state,
+		MinPlayers: match.setup.MinPlayers,
+		TickLimit:  match.setup.TickLimit,
+		Results:    match.results,
+	}
+	if match.state == StateCountdown {
+		startsAt := match.countdownEnd
+		status.StartsAt = &startsAt
+	}
+	if match.state == StateRunning || match.state == StateFinished {
+		startedAt := match.startedAtTick
+		status.StartedAtTick = &startedAt
+	}
+	return status
+}
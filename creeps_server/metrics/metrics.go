@@ -0,0 +1,222 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// a value that can only go up
+// zero value is valid
+type Counter struct {
+	bits atomic.Uint64
+}
+
+func (counter *Counter) Inc() {
+	counter.Add(1)
+}
+
+// panics if value is negative
+func (counter *Counter) Add(value float64) {
+	if value < 0 {
+		panic("counters cannot decrease")
+	}
+	addFloat(&counter.bits, value)
+}
+
+func (counter *Counter) Get() float64 {
+	return math.Float64frombits(counter.bits.Load())
+}
+
+func (counter *Counter) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", counter.Get())
+}
+
+// a value that can go up and down
+// zero value is valid
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+func (gauge *Gauge) Set(value float64) {
+	gauge.bits.Store(math.Float64bits(value))
+}
+
+func (gauge *Gauge) Inc() {
+	gauge.Add(1)
+}
+
+func (gauge *Gauge) Dec() {
+	gauge.Add(-1)
+}
+
+func (gauge *Gauge) Add(value float64) {
+	addFloat(&gauge.bits, value)
+}
+
+func (gauge *Gauge) Get() float64 {
+	return math.Float64frombits(gauge.bits.Load())
+}
+
+func (gauge *Gauge) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", gauge.Get())
+}
+
+func addFloat(bits *atomic.Uint64, value float64) {
+	for {
+		old := bits.Load()
+		new := math.Float64bits(math.Float64frombits(old) + value)
+		if bits.CompareAndSwap(old, new) {
+			return
+		}
+	}
+}
+
+// counts the observed values in buckets, see Registry.NewHistogram
+type Histogram struct {
+	lock    sync.Mutex
+	buckets []float64
+	// not cumulative, the +Inf bucket is count minus the sum of them
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// creates a histogram that is not in any registry, see Registry.AddHistogram
+// buckets are the upper bounds of the buckets in increasing order, the +Inf
+// one is added automatically
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (histogram *Histogram) Observe(value float64) {
+	histogram.lock.Lock()
+	defer histogram.lock.Unlock()
+
+	for i, bound := range histogram.buckets {
+		if value <= bound {
+			histogram.counts[i]++
+			break
+		}
+	}
+	histogram.count++
+	histogram.sum += value
+}
+
+// observes the duration in seconds
+func (histogram *Histogram) ObserveDuration(duration time.Duration) {
+	histogram.Observe(duration.Seconds())
+}
+
+func (histogram *Histogram) write(w *bufio.Writer, name string) {
+	histogram.lock.Lock()
+	counts := slices.Clone(histogram.counts)
+	count := histogram.count
+	sum := histogram.sum
+	histogram.lock.Unlock()
+
+	cumulative := uint64(0)
+	for i, bound := range histogram.buckets {
+		cumulative += counts[i]
+		labels := formatLabels([]string{"le"}, []string{formatValue(bound)})
+		writeSample(w, name+"_bucket", labels, float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", `{le="+Inf"}`, float64(count))
+	writeSample(w, name+"_sum", "", sum)
+	writeSample(w, name+"_count", "", float64(count))
+}
+
+// metrics of the same kind differentiated by labels, created on first use
+type vec[T any] struct {
+	labels []string
+
+	lock sync.RWMutex
+	// indexed by the formatted labels, see formatLabels
+	values map[string]*T
+}
+
+func newVec[T any](labels []string) vec[T] {
+	return vec[T]{
+		labels: labels,
+		values: make(map[string]*T),
+	}
+}
+
+// panics if the amount of values is not the same as the amount of labels
+func (vec *vec[T]) with(labelValues []string) *T {
+	key := formatLabels(vec.labels, labelValues)
+
+	vec.lock.RLock()
+	value, ok := vec.values[key]
+	vec.lock.RUnlock()
+	if ok {
+		return value
+	}
+
+	vec.lock.Lock()
+	defer vec.lock.Unlock()
+	value, ok = vec.values[key]
+	if !ok {
+		value = new(T)
+		vec.values[key] = value
+	}
+	return value
+}
+
+// calls f for every label combination, ordered by labels
+func (vec *vec[T]) forEach(f func(labels string, value *T)) {
+	vec.lock.RLock()
+	keys := make([]string, 0, len(vec.values))
+	for key := range vec.values {
+		keys = append(keys, key)
+	}
+	values := make([]*T, len(keys))
+	slices.SortFunc(keys, strings.Compare)
+	for i, key := range keys {
+		values[i] = vec.values[key]
+	}
+	vec.lock.RUnlock()
+
+	for i, key := range keys {
+		f(key, values[i])
+	}
+}
+
+type CounterVec struct {
+	vec[Counter]
+}
+
+// returns the counter with the given label values, in the order the labels
+// were given to NewCounterVec
+func (vec *CounterVec) With(labelValues ...string) *Counter {
+	return vec.with(labelValues)
+}
+
+func (vec *CounterVec) write(w *bufio.Writer, name string) {
+	vec.forEach(func(labels string, counter *Counter) {
+		writeSample(w, name, labels, counter.Get())
+	})
+}
+
+type GaugeVec struct {
+	vec[Gauge]
+}
+
+// returns the gauge with the given label values, in the order the labels
+// were given to NewGaugeVec
+func (vec *GaugeVec) With(labelValues ...string) *Gauge {
+	return vec.with(labelValues)
+}
+
+func (vec *GaugeVec) write(w *bufio.Writer, name string) {
+	vec.forEach(func(labels string, gauge *Gauge) {
+		writeSample(w, name, labels, gauge.Get())
+	})
+}
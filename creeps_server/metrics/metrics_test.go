@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	tests := []struct {
+		name string
+		// registers and updates the metrics of the registry
+		fill     func(registry *Registry)
+		expected string
+	}{
+		{
+			name:     "empty",
+			fill:     func(registry *Registry) {},
+			expected: "",
+		},
+		{
+			name: "counter",
+			fill: func(registry *Registry) {
+				counter := registry.NewCounter("requests_total", "Requests received")
+				counter.Inc()
+				counter.Add(2.5)
+			},
+			expected: `# HELP requests_total Requests received
+# TYPE requests_total counter
+requests_total 3.5
+`,
+		},
+		{
+			name: "added counter",
+			fill: func(registry *Registry) {
+				counter := new(Counter)
+				counter.Inc()
+				registry.AddCounter("added_total", "Added later", counter)
+				counter.Inc()
+			},
+			expected: `# HELP added_total Added later
+# TYPE added_total counter
+added_total 2
+`,
+		},
+		{
+			name: "gauge",
+			fill: func(registry *Registry) {
+				gauge := registry.NewGauge("temperature", "Current temperature")
+				gauge.Set(10)
+				gauge.Dec()
+			},
+			expected: `# HELP temperature Current temperature
+# TYPE temperature gauge
+temperature 9
+`,
+		},
+		{
+			name: "sorted by name",
+			fill: func(registry *Registry) {
+				registry.NewGauge("b", "Second")
+				registry.NewGauge("a", "First")
+			},
+			expected: `# HELP a First
+# TYPE a gauge
+a 0
+# HELP b Second
+# TYPE b gauge
+b 0
+`,
+		},
+		{
+			name: "vec sorted by labels",
+			fill: func(registry *Registry) {
+				vec := registry.NewCounterVec("commands_total", "Commands", "opcode", "status")
+				vec.With("move", "ok").Inc()
+				vec.With("gather", "error").Add(3)
+				vec.With("move", "ok").Inc()
+			},
+			expected: `# HELP commands_total Commands
+# TYPE commands_total counter
+commands_total{opcode="gather",status="error"} 3
+commands_total{opcode="move",status="ok"} 2
+`,
+		},
+		{
+			name: "escaping",
+			fill: func(registry *Registry) {
+				vec := registry.NewGaugeVec("escaped", "Back\\slash and\nnew line", "value")
+				vec.With("quote\" back\\slash\nnew line").Set(1)
+			},
+			expected: `# HELP escaped Back\\slash and\nnew line
+# TYPE escaped gauge
+escaped{value="quote\" back\\slash\nnew line"} 1
+`,
+		},
+		{
+			name: "special values",
+			fill: func(registry *Registry) {
+				registry.NewGaugeFunc("inf", "Infinite", func() float64 { return math.Inf(1) })
+				registry.NewGaugeFunc("nan", "Not a number", func() float64 { return math.NaN() })
+			},
+			expected: `# HELP inf Infinite
+# TYPE inf gauge
+inf +Inf
+# HELP nan Not a number
+# TYPE nan gauge
+nan NaN
+`,
+		},
+		{
+			name: "histogram",
+			fill: func(registry *Registry) {
+				histogram := registry.NewHistogram("duration_seconds", "Durations", []float64{0.1, 1})
+				histogram.Observe(0.05)
+				histogram.Observe(0.5)
+				histogram.Observe(0.5)
+				histogram.Observe(2)
+			},
+			expected: `# HELP duration_seconds Durations
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 3
+duration_seconds_bucket{le="+Inf"} 4
+duration_seconds_sum 3.05
+duration_seconds_count 4
+`,
+		},
+		{
+			name: "gauge vec func",
+			fill: func(registry *Registry) {
+				registry.NewGaugeVecFunc("entities", "Entities", []string{"type"}, func(set SetFunc) {
+					set(2, "player")
+					set(5, "citizen")
+				})
+			},
+			expected: `# HELP entities Entities
+# TYPE entities gauge
+entities{type="player"} 2
+entities{type="citizen"} 5
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := NewRegistry()
+			test.fill(registry)
+
+			var builder strings.Builder
+			n, err := registry.WriteTo(&builder)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if builder.String() != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, builder.String())
+			}
+			if n != int64(builder.Len()) {
+				t.Errorf("returned %d bytes written, wrote %d", n, builder.Len())
+			}
+		})
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("twice", "Registered twice")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+	registry.NewGauge("twice", "Registered twice")
+}
@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// a set of metrics written together in the prometheus text format
+// see https://prometheus.io/docs/instrumenting/exposition_formats/
+type Registry struct {
+	lock    sync.Mutex
+	metrics map[string]registered
+}
+
+// the registry served by the api's /metrics endpoint
+var Default = NewRegistry()
+
+type metric interface {
+	// writes the samples of the metric, without the HELP and TYPE lines
+	write(w *bufio.Writer, name string)
+}
+
+type registered struct {
+	metric
+	kind string
+	help string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]registered),
+	}
+}
+
+// panics if a metric with the same name already exists
+func (registry *Registry) register(name string, kind string, help string, m metric) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	if _, exists := registry.metrics[name]; exists {
+		panic(fmt.Errorf("metric %s registered twice", name))
+	}
+	registry.metrics[name] = registered{
+		metric: m,
+		kind:   kind,
+		help:   help,
+	}
+}
+
+func (registry *Registry) NewCounter(name string, help string) *Counter {
+	counter := new(Counter)
+	registry.register(name, "counter", help, counter)
+	return counter
+}
+
+// registers a counter created outside of any registry (ex: new(Counter)), to
+// keep it per instance of what it counts
+func (registry *Registry) AddCounter(name string, help string, counter *Counter) {
+	registry.register(name, "counter", help, counter)
+}
+
+func (registry *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	vec := &CounterVec{vec: newVec[Counter](labels)}
+	registry.register(name, "counter", help, vec)
+	return vec
+}
+
+func (registry *Registry) NewGauge(name string, help string) *Gauge {
+	gauge := new(Gauge)
+	registry.register(name, "gauge", help, gauge)
+	return gauge
+}
+
+// see AddCounter
+func (registry *Registry) AddGauge(name string, help string, gauge *Gauge) {
+	registry.register(name, "gauge", help, gauge)
+}
+
+func (registry *Registry) NewGaugeVec(name string, help string, labels ...string) *GaugeVec {
+	vec := &GaugeVec{vec: newVec[Gauge](labels)}
+	registry.register(name, "gauge", help, vec)
+	return vec
+}
+
+// buckets are the upper bounds of the buckets in increasing order, the +Inf
+// one is added automatically
+func (registry *Registry) NewHistogram(name string, help string, buckets []float64) *Histogram {
+	histogram := NewHistogram(buckets)
+	registry.register(name, "histogram", help, histogram)
+	return histogram
+}
+
+// see AddCounter
+func (registry *Registry) AddHistogram(name string, help string, histogram *Histogram) {
+	registry.register(name, "histogram", help, histogram)
+}
+
+// the value is computed by calling f each time the metrics are collected
+func (registry *Registry) NewGaugeFunc(name string, help string, f func() float64) {
+	registry.register(name, "gauge", help, funcMetric(func(set sampleFunc) {
+		set(f(), "")
+	}))
+}
+
+// the value is computed by calling f each time the metrics are collected, it
+// must never decrease
+func (registry *Registry) NewCounterFunc(name string, help string, f func() float64) {
+	registry.register(name, "counter", help, funcMetric(func(set sampleFunc) {
+		set(f(), "")
+	}))
+}
+
+// f is called each time the metrics are collected and must call set once for
+// every combination of label values with the value of the gauge
+func (registry *Registry) NewGaugeVecFunc(
+	name string,
+	help string,
+	labels []string,
+	f func(set SetFunc),
+) {
+	registry.register(name, "gauge", help, funcMetric(func(set sampleFunc) {
+		f(func(value float64, labelValues ...string) {
+			set(value, formatLabels(labels, labelValues))
+		})
+	}))
+}
+
+// writes every metric, ordered by name
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.lock.Lock()
+	names := make([]string, 0, len(registry.metrics))
+	for name := range registry.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]registered, 0, len(names))
+	slices.Sort(names)
+	for _, name := range names {
+		metrics = append(metrics, registry.metrics[name])
+	}
+	registry.lock.Unlock()
+
+	counter := &countingWriter{w: w}
+	writer := bufio.NewWriter(counter)
+	for i, m := range metrics {
+		fmt.Fprintf(writer, "# HELP %s %s\n", names[i], escapeHelp(m.help))
+		fmt.Fprintf(writer, "# TYPE %s %s\n", names[i], m.kind)
+		m.write(writer, names[i])
+	}
+	err := writer.Flush()
+	return counter.n, err
+}
+
+// serves the metrics in the prometheus text format
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(200)
+	registry.WriteTo(w)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (writer *countingWriter) Write(p []byte) (int, error) {
+	n, err := writer.w.Write(p)
+	writer.n += int64(n)
+	return n, err
+}
+
+// called with the value of a sample and, for vecs, the values of its labels
+type SetFunc func(value float64, labelValues ...string)
+
+// like SetFunc but with the labels already formatted, see formatLabels
+type sampleFunc func(value float64, labels string)
+
+type funcMetric func(set sampleFunc)
+
+func (f funcMetric) write(w *bufio.Writer, name string) {
+	f(func(value float64, labels string) {
+		writeSample(w, name, labels, value)
+	})
+}
+
+// labels is already formatted, see formatLabels
+func writeSample(w *bufio.Writer, name string, labels string, value float64) {
+	w.WriteString(name)
+	w.WriteString(labels)
+	w.WriteByte(' ')
+	w.WriteString(formatValue(value))
+	w.WriteByte('\n')
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// returns {name="value",...} or an empty string if there are no labels
+func formatLabels(names []string, values []string) string {
+	if len(names) != len(values) {
+		panic(fmt.Errorf("expected %d label values, got %d", len(names), len(values)))
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(name)
+		builder.WriteString(`="`)
+		builder.WriteString(labelValueReplacer.Replace(values[i]))
+		builder.WriteByte('"')
+	}
+	builder.WriteByte('}')
+	return builder.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -11,8 +11,12 @@ import (
 type Action struct {
 	OpCode        model.ActionOpCode
 	StartedAtTick int
-	ReportId      uid.Uid
-	Finised       atomic.Bool
+	// cast time in ticks, set when the action is started
+	Cast int
+	// tick at which the action is resolved, set when the action is started
+	FinishedAtTick int
+	ReportId       uid.Uid
+	Finised        atomic.Bool
 	// Contains the type returned by OpCode.ParameterType()
 	Parameter any
 }
@@ -89,7 +89,9 @@ func refine(
 
 		(*resources.OfKind(to))++
 
-		report = &model.RefineReport{}
+		report = &model.RefineReport{
+			Produced: 1,
+		}
 		return resources
 	})
 
@@ -181,6 +183,90 @@ func build(
 		return t
 	})
 
+	// done after to not take the player's lock while holding the chunk's one
+	if _, failed := report.(*model.ErrorReport); report != nil && !failed {
+		player.AddBuilding(position, target)
+	}
+
+	return
+}
+
+// the refund given when dismantling a building is its cost divided by this
+const dismantleRefundDivisor = 2
+
+// returns the building opcode and the cost of building the given tile kind
+// or an empty opcode if the tile isn't a building
+func buildingOfTile(costs *model.CostsResponse, kind terrain.TileKind) (string, *model.CostResponse) {
+	switch kind {
+	case terrain.TileTownHall:
+		return "town-hall", &costs.BuildTownHall
+	case terrain.TileHousehold:
+		return "household", &costs.BuildHousehold
+	case terrain.TileSawMill:
+		return "sawmill", &costs.BuildSawmill
+	case terrain.TileSmeltery:
+		return "smeltery", &costs.BuildSmeltery
+	case terrain.TileRoad:
+		return "road", &costs.BuildRoad
+	}
+	return "", nil
+}
+
+// used by ApplyAction
+func dismantle(unit IUnit) (report model.IReport) {
+	server := unit.GetServer()
+	ownerId := unit.GetOwner()
+	position := unit.GetPosition()
+	player, ok := server.GetEntity(ownerId).(*Player)
+
+	if !ok {
+		log.Warn().Any("ownerId", ownerId).Msg("no player no dismantle")
+		return
+	}
+
+	server.Tilemap().ModifyTile(position, func(t terrain.Tile) terrain.Tile {
+		opcode, cost := buildingOfTile(server.GetCosts(), t.Kind)
+		if cost == nil {
+			report = &model.ErrorReport{
+				Error:     "There is no building to dismantle here",
+				ErrorCode: "not-a-building",
+			}
+			return t
+		}
+
+		// removed with the tile lock so the building cannot be dismantled
+		// twice, and by its owner only
+		if !player.RemoveBuilding(position) {
+			report = &model.ErrorReport{
+				Error:     "Cannot dismantle someone else's building",
+				ErrorCode: "not-owner",
+			}
+			return t
+		}
+		if t.Kind == terrain.TileTownHall {
+			player.RemoveTownHall(position)
+		}
+
+		refund := cost.Resources.Div(dismantleRefundDivisor)
+		player.ModifyResources(func(res model.Resources) model.Resources {
+			return res.Sum(refund)
+		})
+
+		report = &model.DismantleReport{
+			Building: model.Building{
+				OpCode:   opcode,
+				Player:   69,
+				Position: position,
+			},
+			RefundedResources: refund,
+		}
+
+		return terrain.Tile{
+			Kind:  terrain.TileGrass,
+			Value: 0,
+		}
+	})
+
 	return
 }
 
@@ -219,28 +305,162 @@ func spawn[T IUnit](
 	}
 
 	precreatedUnit.SetPosition(unit.GetPosition())
-	server.RegisterEntity(precreatedUnit)
+	precreatedUnit.Register()
+
+	report = &model.SpawnReport{
+		SpawnedUnitId: precreatedUnit.GetId(),
+		SpawnedUnit:   reportUnit(server, precreatedUnit),
+	}
+
+	return
+}
+
+// used by ApplyAction
+func sendMessage(
+	unit IUnit,
+	sender *Player,
+	parameter model.MessageSendParameter,
+) model.IReport {
+	server := unit.GetServer()
+
+	if len(parameter.Message) > MaxMessageLength {
+		return &model.ErrorReport{
+			ErrorCode: "message-too-long",
+			Error:     "The message is too long",
+		}
+	}
+
+	recipient, _ := server.FindEntity(func(e IEntity) bool {
+		if p, ok := e.(*Player); ok {
+			return p.GetUsername() == parameter.Recipient
+		}
+		return false
+	}).(*Player)
 
+	if recipient == nil {
+		return &model.ErrorReport{
+			ErrorCode: "unknown-recipient",
+			Error:     "No player with this login",
+		}
+	}
+
+	if !recipient.ReceiveMessage(sender, parameter.Message) {
+		return &model.ErrorReport{
+			ErrorCode: "mailbox-full",
+			Error:     "The recipient's mailbox is full",
+		}
+	}
+
+	return &model.MessageSendReport{
+		Recipient: recipient.GetUsername(),
+	}
+}
+
+// converts the given unit into its report representation
+func reportUnit(server *Server, unit IUnit) model.Unit {
 	playerUsername := "server"
-	if player, ok := unit.GetServer().GetEntity(precreatedUnit.GetId()).(*Player); ok {
+	if player, ok := server.GetEntity(unit.GetOwner()).(*Player); ok {
 		playerUsername = player.GetUsername()
 	}
 
-	report = &model.SpawnReport{
-		SpawnedUnitId: precreatedUnit.GetId(),
-		SpawnedUnit: model.Unit{
-			OpCode:   precreatedUnit.GetOpCode(),
-			Player:   playerUsername,
-			Position: precreatedUnit.GetPosition(),
-		},
+	return model.Unit{
+		OpCode:   unit.GetOpCode(),
+		Player:   playerUsername,
+		Position: unit.GetPosition(),
 	}
+}
+
+// distance used for fire range checks (the biggest of both axis)
+func fireDistance(from Point, to Point) int {
+	diff := to.Sub(from)
+	return mathutils.Max(mathutils.AbsInt(diff.X), mathutils.AbsInt(diff.Y))
+}
 
+// replaces the building at the given position (if any) by grass
+// and removes it from its owner
+func destroyBuilding(server *Server, position Point) (building model.Building, destroyed bool) {
+	wasTownHall := false
+	server.Tilemap().ModifyTile(position, func(t terrain.Tile) terrain.Tile {
+		opcode, _ := buildingOfTile(server.GetCosts(), t.Kind)
+		if opcode == "" {
+			return t
+		}
+
+		wasTownHall = t.Kind == terrain.TileTownHall
+		destroyed = true
+		building = model.Building{
+			OpCode:   opcode,
+			Player:   69,
+			Position: position,
+		}
+		return terrain.Tile{
+			Kind:  terrain.TileGrass,
+			Value: 0,
+		}
+	})
+
+	// done after to not take the players lock while holding the chunk's one
+	if destroyed {
+		server.ForEachEntity(func(entity IEntity) (shouldStop bool) {
+			if player, ok := entity.(*Player); ok {
+				if wasTownHall {
+					player.RemoveTownHall(position)
+				}
+				shouldStop = player.RemoveBuilding(position)
+			}
+			return
+		})
+	}
 	return
 }
 
+// used by ApplyAction
+// kills every units in the given radius (cube "radius", 0 being only the target
+// tile) around the target, and destroys buildings too if asked to
+func fire(
+	unit IUnit,
+	target Point,
+	radius int,
+	destroyBuildings bool,
+) model.IReport {
+	server := unit.GetServer()
+	area := AABB{
+		From: target.Minus(radius, radius),
+		Size: Point{X: radius*2 + 1, Y: radius*2 + 1},
+	}
+
+	killed := make([]model.Unit, 0)
+	for _, entity := range server.Entities().GetAllIntersects(area) {
+		victim, ok := entity.(IUnit)
+		if !ok || !victim.IsRegistered() {
+			continue
+		}
+		killed = append(killed, reportUnit(server, victim))
+		victim.Unregister()
+	}
+
+	var destroyed []model.Building
+	if destroyBuildings {
+		for y := area.From.Y; y < area.Upto().Y; y++ {
+			for x := area.From.X; x < area.Upto().X; x++ {
+				if building, ok := destroyBuilding(server, Point{X: x, Y: y}); ok {
+					destroyed = append(destroyed, building)
+				}
+			}
+		}
+	}
+
+	return &model.FireReport{
+		Target:             target,
+		KilledUnits:        killed,
+		DestroyedBuildings: destroyed,
+	}
+}
+
 // called by unit in units/unit.go when the action is finished
 func ApplyAction(action *Action, unit IUnit) model.IReport {
 	server := unit.GetServer()
+	stats := unit.(extendedUnit).getUnit().GetStats()
 	owner := server.GetEntityOwner(unit.GetId())
 	player, _ := owner.(*Player)
 	oldPosition := unit.GetPosition()
@@ -268,13 +488,36 @@ func ApplyAction(action *Action, unit IUnit) model.IReport {
 	case model.OpCodeMoveUp:
 		fallthrough
 	case model.OpCodeMoveDown:
-		var newPos Point
-		unit.ModifyPosition(func(pos Point) Point {
-			newPos = pos.Add(action.OpCode.MoveDirection())
-			return newPos
-		})
+		newPos := oldPosition.Add(action.OpCode.MoveDirection())
+
+		if !server.GetSetup().IsInWorld(newPos) {
+			report = &model.ErrorReport{
+				ErrorCode: "out-of-world",
+				Error:     "This is the end of the world",
+			}
+			break
+		}
+
+		if !canWalkOn(unit, newPos) {
+			report = &model.ErrorReport{
+				ErrorCode: "tile-not-walkable",
+				Error:     "Cannot walk on this tile",
+			}
+			break
+		}
+
+		if _, isRaider := unit.(*RaiderUnit); !isRaider && tileIsFull(server, newPos) {
+			report = &model.ErrorReport{
+				ErrorCode: "tile-full",
+				Error:     "There are too many units on this tile",
+			}
+			break
+		}
+
+		unit.SetPosition(newPos)
 		mv := &model.MoveReport{
 			NewPosition: newPos,
+			Cast:        action.Cast,
 		}
 		observe(unit, &mv.ObserveReport)
 		report = mv
@@ -283,7 +526,7 @@ func ApplyAction(action *Action, unit IUnit) model.IReport {
 		observe(unit, mv)
 		report = mv
 	case model.OpCodeGather:
-		maxInventorySize := server.GetSetup().MaxLoad
+		maxInventorySize := server.GetSetup().MaxLoad + stats.ExtraLoad
 		position := unit.GetPosition()
 
 		server.Tilemap().ModifyTile(position, func(tile terrain.Tile) terrain.Tile {
@@ -400,7 +643,7 @@ func ApplyAction(action *Action, unit IUnit) model.IReport {
 			return tile
 		})
 	case model.OpCodeDismantle:
-		report = &model.DismantleReport{}
+		report = dismantle(unit)
 	case model.OpCodeUpgrade:
 		if unit.IsUpgraded() {
 			report = &model.ErrorReport{
@@ -430,6 +673,7 @@ func ApplyAction(action *Action, unit IUnit) model.IReport {
 			break
 		}
 
+		unit.SetUpgraded()
 		report = &model.UpgradeReport{}
 	case model.OpCodeRefineCopper:
 		fallthrough
@@ -454,15 +698,21 @@ func ApplyAction(action *Action, unit IUnit) model.IReport {
 	case model.OpCodeSpawnBomberBot:
 		report = spawn(
 			unit,
-			&server.GetCosts().SpawnTurret,
-			NewTurretUnit(server, unit.GetOwner()),
+			&server.GetCosts().SpawnBomberBot,
+			NewBomberBotUnit(server, unit.GetOwner()),
 		)
 	case model.OpCodeFireTurret:
 		parameter := action.Parameter.(model.FireParameter)
 
-		distance := parameter.Destination.Sub(oldPosition)
+		if !server.GetSetup().IsInWorld(parameter.Destination) {
+			report = &model.ErrorReport{
+				ErrorCode: "out-of-world",
+				Error:     "You are shooting outside of the world",
+			}
+			break
+		}
 
-		if mathutils.Max(distance.X, distance.Y) > unit.ObserveDistance() {
+		if fireDistance(oldPosition, parameter.Destination) > stats.FireRange {
 			report = &model.ErrorReport{
 				ErrorCode: "out-of-range",
 				Error:     "You are reaching too far !!",
@@ -478,31 +728,42 @@ func ApplyAction(action *Action, unit IUnit) model.IReport {
 			break
 		}
 
-		killed := make([]model.Unit, 0)
+		report = fire(unit, parameter.Destination, 0, false)
+	case model.OpCodeFireBomberBot:
+		parameter := action.Parameter.(model.FireParameter)
+		radius := stats.BlastRadius
+
+		if !server.GetSetup().IsInWorld(parameter.Destination) {
+			report = &model.ErrorReport{
+				ErrorCode: "out-of-world",
+				Error:     "You are shooting outside of the world",
+			}
+			break
+		}
+
+		if fireDistance(oldPosition, parameter.Destination) > stats.FireRange {
+			report = &model.ErrorReport{
+				ErrorCode: "out-of-range",
+				Error:     "You are reaching too far !!",
+			}
+			break
+		}
 
-		entities := server.Entities().GetAllIntersects(AABB{
-			From: parameter.Destination,
-			Size: Point{X: 1, Y: 1},
-		})
-		for _, entity := range entities {
-			unit, ok := entity.(IUnit)
-			if !ok {
-				continue
+		if fireDistance(oldPosition, parameter.Destination) <= radius {
+			report = &model.ErrorReport{
+				ErrorCode: "bomber-bot-minimum-range",
+				Error:     "The bomb would blow you up too",
 			}
-			killed = append(killed, model.Unit{
-				OpCode:   unit.GetOpCode(),
-				Player:   string(unit.GetOwner()),
-				Position: unit.GetPosition(),
-			})
-			entity.Unregister()
+			break
 		}
 
-		report = &model.FireReport{
-			Target:      parameter.Destination,
-			KilledUnits: killed,
+		report = fire(unit, parameter.Destination, radius, true)
+	case model.OpCodeMessageSend:
+		report = sendMessage(unit, player, action.Parameter.(model.MessageSendParameter))
+	case model.OpCodeMessageFetch:
+		report = &model.MessageFetchReport{
+			FetchedMessages: player.FetchMessages(),
 		}
-	case model.OpCodeFireBomberBot:
-		panic("uniplemented")
 	}
 
 end:
@@ -510,6 +771,8 @@ end:
 	report.GetReport().OpCode = action.OpCode
 	report.GetReport().UnitId = unit.GetId()
 	report.GetReport().UnitPosition = oldPosition
+	report.GetReport().StartedAtTick = action.StartedAtTick
+	report.GetReport().FinishedAtTick = action.FinishedAtTick
 	report.GetReport().Status = "SUCCESS"
 	if player != nil {
 		report.GetReport().Login = player.GetUsername()
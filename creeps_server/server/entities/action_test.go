@@ -0,0 +1,81 @@
+package entities_test
+
+import (
+	"testing"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+func TestDismantle(t *testing.T) {
+	tests := []struct {
+		name string
+		kind terrain.TileKind
+		// the building belongs to the other player
+		foreign bool
+		// empty if the dismantle must succeed
+		errorCode string
+	}{
+		{name: "own sawmill", kind: terrain.TileSawMill},
+		{name: "own road", kind: terrain.TileRoad},
+		{name: "own town hall", kind: terrain.TileTownHall},
+		{name: "foreign sawmill", kind: terrain.TileSawMill, foreign: true, errorCode: "not-owner"},
+		{name: "foreign smeltery", kind: terrain.TileSmeltery, foreign: true, errorCode: "not-owner"},
+		{name: "foreign town hall", kind: terrain.TileTownHall, foreign: true, errorCode: "not-owner"},
+		{name: "grass", kind: terrain.TileGrass, errorCode: "not-a-building"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := servertest.NewServer(0)
+			alice, citizen, _ := servertest.SpawnPlayer(srv, "alice", Point{})
+			bob, _, _ := servertest.SpawnPlayer(srv, "bob", Point{X: 40})
+
+			owner := alice
+			if test.foreign {
+				owner = bob
+			}
+			position := citizen.GetPosition()
+			srv.Tilemap().SetTile(position, terrain.Tile{Kind: test.kind})
+			alice.RemoveBuilding(position)
+			if test.kind != terrain.TileGrass {
+				owner.AddBuilding(position, test.kind)
+			}
+			resources := alice.GetResources()
+
+			report := applyAction(citizen, model.OpCodeDismantle)
+			if test.errorCode != "" {
+				errorReport, ok := report.(*model.ErrorReport)
+				if !ok || errorReport.ErrorCode != test.errorCode {
+					t.Fatalf("expected the error %s, got %+v", test.errorCode, report)
+				}
+				if alice.GetResources() != resources {
+					t.Errorf("resources changed from %+v to %+v", resources, alice.GetResources())
+				}
+				if test.foreign && !bob.HasBuilding(position) {
+					t.Errorf("the owner lost its building")
+				}
+				if kind := srv.Tilemap().GetTile(position).Kind; kind != test.kind {
+					t.Errorf("expected the tile to stay %d, got %d", test.kind, kind)
+				}
+				return
+			}
+
+			dismantled, ok := report.(*model.DismantleReport)
+			if !ok {
+				t.Fatalf("expected a dismantle report, got %+v", report)
+			}
+			if alice.GetResources() != resources.Sum(dismantled.RefundedResources) {
+				t.Errorf("expected %+v to be refunded", dismantled.RefundedResources)
+			}
+			if alice.HasBuilding(position) {
+				t.Errorf("the building is still owned")
+			}
+			if kind := srv.Tilemap().GetTile(position).Kind; kind != terrain.TileGrass {
+				t.Errorf("expected grass, got %d", kind)
+			}
+		})
+	}
+}
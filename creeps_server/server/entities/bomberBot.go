@@ -39,16 +39,13 @@ func (bomberBot *BomberBotUnit) GetOwner() uid.Uid {
 	return bomberBot.owner
 }
 
-func (bomberBot *BomberBotUnit) ObserveDistance() int {
-	if bomberBot.IsUpgraded() {
-		// FIXME: Is it correct ?
-		return 7
-	}
-	return 5
-}
-
 func (bomberBot *BomberBotUnit) StartAction(action *Action, onFinished func()) error {
 	err := bomberBot.startAction(action, []model.ActionOpCode{
+		model.OpCodeMoveDown,
+		model.OpCodeMoveUp,
+		model.OpCodeMoveLeft,
+		model.OpCodeMoveRight,
+
 		model.OpCodeUpgrade,
 		model.OpCodeFireBomberBot,
 	}, onFinished)
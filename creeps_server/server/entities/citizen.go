@@ -42,14 +42,6 @@ func (citizen *CitizenUnit) GetOwner() uid.Uid {
 	return citizen.owner
 }
 
-func (citizen *CitizenUnit) ObserveDistance() int {
-	if citizen.IsUpgraded() {
-		// FIXME: Is it correct ?
-		return 7
-	}
-	return 6
-}
-
 func (citizen *CitizenUnit) StartAction(action *Action, onFinished func()) error {
 	err := citizen.startAction(action, []model.ActionOpCode{
 		model.OpCodeMoveDown,
@@ -62,6 +54,7 @@ func (citizen *CitizenUnit) StartAction(action *Action, onFinished func()) error
 		model.OpCodeBuildSawmill,
 		model.OpCodeBuildSmeltery,
 		model.OpCodeBuildTownHall,
+		model.OpCodeDismantle,
 
 		model.OpCodeUpgrade,
 		model.OpCodeSpawnBomberBot,
@@ -74,6 +67,9 @@ func (citizen *CitizenUnit) StartAction(action *Action, onFinished func()) error
 		model.OpCodeRefineWoodPlank,
 
 		model.OpCodeObserve,
+
+		model.OpCodeMessageSend,
+		model.OpCodeMessageFetch,
 	}, onFinished)
 	if err != nil {
 		return err
@@ -98,10 +94,7 @@ func (citizen *CitizenUnit) Tick() {
 	}
 
 	feedInterval := server.GetSetup().CitizenFeedingRate
-	feedAmount := 1
-	if citizen.IsUpgraded() {
-		feedAmount = 2
-	}
+	feedAmount := citizen.GetStats().FeedAmount
 
 	if ticker.GetTickNumber()-citizen.lastEatenAt > feedInterval {
 		var couldFeed bool
@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"math"
+
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
+)
+
+// returns the difficulty of the enemies attacking the player
+// see model.DifficultySetup
+// must not be called with the lock, see GetBuildingPositions
+func (player *Player) GetDifficulty() float64 {
+	curve := player.server.GetSetup().EnemyDifficulty
+
+	difficulty := 1 +
+		curve.PerTick*float64(player.server.Ticker().GetTickNumber()) +
+		curve.PerBuilding*float64(player.GetBuildingCount()) +
+		curve.PerUnit*float64(player.GetUnitCount())
+
+	if curve.Max > 0 && difficulty > curve.Max {
+		difficulty = curve.Max
+	}
+	return difficulty
+}
+
+// divides the tick rate by the difficulty (never going under one tick)
+func scaleTickRate(rate int, difficulty float64) int {
+	return mathutils.Max(1, int(float64(rate)/difficulty))
+}
+
+// multiplies the amount by the difficulty (never going under one)
+func scaleAmount(amount int, difficulty float64) int {
+	return mathutils.Max(1, int(math.Round(float64(amount)*difficulty)))
+}
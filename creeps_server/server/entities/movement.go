@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"math"
+
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	. "github.com/heavenston/creeps_server/creeps_server/server"
+)
+
+// see model.MoveFactors
+func moveFactor(factors *model.MoveFactors, kind terrain.TileKind) float64 {
+	factor := 0.
+	switch kind {
+	case terrain.TileRoad:
+		factor = factors.Road
+	case terrain.TileBush:
+		factor = factors.Bush
+	case terrain.TileTree:
+		factor = factors.Tree
+	}
+	if factor <= 0 {
+		return 1
+	}
+	return factor
+}
+
+// returns the cast time of the given move for the unit depending on the tile
+// it is on and the one it goes to (never less than one tick)
+func moveCast(unit IUnit, opcode model.ActionOpCode) int {
+	server := unit.GetServer()
+	factors := &server.GetSetup().MoveFactors
+	from := unit.GetPosition()
+	to := from.Add(opcode.MoveDirection())
+
+	factor := (moveFactor(factors, server.Tilemap().GetTile(from).Kind) +
+		moveFactor(factors, server.Tilemap().GetTile(to).Kind)) / 2
+
+	cast := float64(server.GetCosts().Move.Cast) * factor
+	return mathutils.Max(1, int(math.Round(cast)))
+}
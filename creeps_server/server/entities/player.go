@@ -1,6 +1,9 @@
 package entities
 
 import (
+	"crypto/subtle"
+	"maps"
+	"slices"
 	"sync"
 	"sync/atomic"
 
@@ -14,6 +17,13 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// maximum amount of messages waiting in a player's mailbox, new messages are
+// refused when it is full
+const MaxMailboxSize = 64
+
+// maximum length (in bytes) of a single message
+const MaxMessageLength = 1024
+
 type Player struct {
 	OwnerEntity
 
@@ -28,10 +38,26 @@ type Player struct {
 	username   string
 	addr       string
 	spawnPoint Point
+	// secret given to the client at init to authenticate its requests, empty
+	// if the player has none
+	token string
 
 	resources model.Resources
 
 	townHalls []Point
+	// every building built by the player (town halls included) with its kind
+	buildings map[Point]terrain.TileKind
+
+	// messages received but not yet fetched, oldest first
+	mailbox []model.Message
+
+	// count of failed commands
+	misses int
+
+	// names of the achievements awarded to the player in order
+	achievements []string
+	// copper refined since the player joined, see AddRefinedCopper
+	refinedCopper int
 
 	lastEnemySpawnTick int
 }
@@ -45,11 +71,12 @@ func NewPlayer(
 	player := new(Player)
 
 	player.OwnerEntity.InitOwnedEntities()
+	player.buildings = make(map[Point]terrain.TileKind)
 
 	player.server = server
 	player.spawnPoint = spawnPoint
 	player.addr = addr
-	player.id = uid.GenUid()
+	player.id = server.GenUid()
 	player.username = username
 	player.lastEnemySpawnTick = server.Ticker().GetTickNumber()
 
@@ -72,6 +99,19 @@ func (player *Player) GetAddr() string {
 	return player.addr
 }
 
+// must be called before the player is registered
+func (player *Player) SetToken(token string) {
+	player.token = token
+}
+
+// false if the player has no token
+func (player *Player) CheckToken(token string) bool {
+	if player.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(player.token), []byte(token)) == 1
+}
+
 func (player *Player) GetSpawnPoint() Point {
 	return player.spawnPoint
 }
@@ -102,18 +142,29 @@ func (player *Player) GetResources() model.Resources {
 // Do not call for modification after GetResources, to avoid race conditions use
 // modify resources
 func (player *Player) SetResources(resources model.Resources) {
-	player.lock.Lock()
-	defer player.lock.Unlock()
-
-	player.resources = resources
+	player.ModifyResources(func(model.Resources) model.Resources {
+		return resources
+	})
 }
 
 // atomically modifies the resources
+// emits a PlayerResourcesEvent if they changed and the player is registered
 func (player *Player) ModifyResources(f func(res model.Resources) model.Resources) {
 	player.lock.Lock()
 	defer player.lock.Unlock()
 
-	player.resources = f(player.resources)
+	old := player.resources
+	player.resources = f(old)
+
+	// emitted with the lock (Emit never blocks) so the events of concurrent
+	// changes are received in the order the changes happened
+	if player.resources == old || !player.IsRegistered() {
+		return
+	}
+	player.server.Events().Emit(&PlayerResourcesEvent{
+		Player:    player,
+		Resources: player.resources,
+	})
 }
 
 func (player *Player) GetTownHalls() []Point {
@@ -169,6 +220,202 @@ func (player *Player) RemoveTownHall(p Point) bool {
 	return false
 }
 
+func (player *Player) AddBuilding(p Point, kind terrain.TileKind) {
+	player.lock.Lock()
+	defer player.lock.Unlock()
+
+	player.buildings[p] = kind
+}
+
+func (player *Player) HasBuilding(p Point) bool {
+	player.lock.RLock()
+	defer player.lock.RUnlock()
+
+	_, has := player.buildings[p]
+	return has
+}
+
+// returns true if it was removed
+// false if the player had no building there
+func (player *Player) RemoveBuilding(p Point) bool {
+	player.lock.Lock()
+	defer player.lock.Unlock()
+
+	_, had := player.buildings[p]
+	delete(player.buildings, p)
+	return had
+}
+
+// counts the buildings of the player that are still standing
+func (player *Player) GetBuildingCount() int {
+	return len(player.GetBuildingPositions())
+}
+
+// returns the positions of the buildings of the player that are still standing
+func (player *Player) GetBuildingPositions() []Point {
+	// the tiles are read without the lock, actions modify a tile and then the
+	// player while holding the chunk lock
+	player.lock.RLock()
+	buildings := maps.Clone(player.buildings)
+	player.lock.RUnlock()
+
+	positions := make([]Point, 0, len(buildings))
+	for p, kind := range buildings {
+		if player.server.Tilemap().GetTile(p).Kind == kind {
+			positions = append(positions, p)
+		}
+	}
+	// map order is random
+	slices.SortFunc(positions, ComparePoints)
+	return positions
+}
+
+// counts the units owned by the player
+func (player *Player) GetUnitCount() int {
+	count := 0
+	player.ForEachEntities(func(entity IEntity) (shouldStop bool) {
+		if _, ok := entity.(IUnit); ok {
+			count++
+		}
+		return
+	})
+	return count
+}
+
+// adds the message to the player's mailbox
+// returns false if the mailbox is full
+func (player *Player) ReceiveMessage(sender *Player, message string) bool {
+	player.lock.Lock()
+	if len(player.mailbox) >= MaxMailboxSize {
+		player.lock.Unlock()
+		return false
+	}
+	player.mailbox = append(player.mailbox, model.Message{
+		Sender:  sender.GetUsername(),
+		Message: message,
+	})
+	player.lock.Unlock()
+
+	player.server.Events().Emit(&PlayerMessageEvent{
+		Sender:    sender,
+		Recipient: player,
+		Message:   message,
+	})
+
+	return true
+}
+
+// empties the mailbox and returns all messages it contained
+func (player *Player) FetchMessages() []model.Message {
+	player.lock.Lock()
+	defer player.lock.Unlock()
+
+	messages := player.mailbox
+	player.mailbox = nil
+	if messages == nil {
+		messages = make([]model.Message, 0)
+	}
+	return messages
+}
+
+func (player *Player) GetMisses() int {
+	player.lock.RLock()
+	defer player.lock.RUnlock()
+	return player.misses
+}
+
+// counts a failed command for the player and the given unit (can be nil)
+// when the unit has more than MaxMissesPerUnit misses or the player more than
+// MaxMissesPerPlayer, the setup's MissPenalty is applied
+// returns the player's new miss count
+func (player *Player) AddMiss(unit IUnit) int {
+	setup := player.server.GetSetup()
+
+	player.lock.Lock()
+	player.misses++
+	misses := player.misses
+	player.lock.Unlock()
+
+	unitMisses := 0
+	if unit != nil {
+		unitMisses = unit.AddMiss()
+	}
+
+	playerExceeded := setup.MaxMissesPerPlayer > 0 && misses > setup.MaxMissesPerPlayer
+	unitExceeded := setup.MaxMissesPerUnit > 0 && unitMisses > setup.MaxMissesPerUnit
+	if !playerExceeded && !unitExceeded {
+		return misses
+	}
+
+	switch setup.MissPenalty {
+	case model.MissPenaltyKillUnit:
+		if unit != nil && unit.IsRegistered() {
+			log.Info().
+				Str("player_id", string(player.id)).
+				Str("unit_id", string(unit.GetId())).
+				Int("misses", misses).
+				Int("unit_misses", unitMisses).
+				Msg("Unit killed for missing too many commands")
+			unit.Unregister()
+		}
+	case model.MissPenaltyDisconnect:
+		if player.IsRegistered() {
+			log.Info().
+				Str("player_id", string(player.id)).
+				Int("misses", misses).
+				Int("unit_misses", unitMisses).
+				Msg("Player disconnected for missing too many commands")
+			player.Unregister()
+		}
+	}
+
+	return misses
+}
+
+func (player *Player) GetAchievements() []string {
+	player.lock.RLock()
+	defer player.lock.RUnlock()
+
+	achievements := make([]string, len(player.achievements))
+	copy(achievements, player.achievements)
+	return achievements
+}
+
+// adds to the copper refined by the player and returns the new total
+func (player *Player) AddRefinedCopper(amount int) int {
+	player.lock.Lock()
+	defer player.lock.Unlock()
+
+	player.refinedCopper += amount
+	return player.refinedCopper
+}
+
+// awards the given achievement to the player
+// returns false if the player already had it
+func (player *Player) AddAchievement(name string) bool {
+	player.lock.Lock()
+	for _, a := range player.achievements {
+		if a == name {
+			player.lock.Unlock()
+			return false
+		}
+	}
+	player.achievements = append(player.achievements, name)
+	player.lock.Unlock()
+
+	log.Info().
+		Str("player_id", string(player.id)).
+		Str("achievement", name).
+		Msg("Player got an achievement")
+
+	player.server.Events().Emit(&PlayerAchievementEvent{
+		Player:      player,
+		Achievement: name,
+	})
+
+	return true
+}
+
 func (player *Player) Register() {
 	player.server.RegisterEntity(player)
 	player.isRegistred.Store(true)
@@ -202,7 +449,7 @@ func (player *Player) enemySpawnTick() {
 	currentTick := player.server.Ticker().GetTickNumber()
 
 	elapsed := currentTick - player.lastEnemySpawnTick
-	rate := player.server.GetSetup().EnemyBaseTickRate
+	rate := scaleTickRate(player.server.GetSetup().EnemyBaseTickRate, player.GetDifficulty())
 
 	if elapsed <= rate {
 		return
@@ -210,16 +457,26 @@ func (player *Player) enemySpawnTick() {
 
 	player.lastEnemySpawnTick = currentTick
 
-	// finding spawn point can be costly so use another goroutine
-	go (func() {
+	// on the ticker at the end of the tick (and not in another goroutine)
+	// so the raid's uid, camp and first tick only depend on the seed
+	player.server.Ticker().Defer(func() {
 		NewRaid(player.server, player.id).Register()
-	})()
+	})
 }
 
 func (player *Player) Tick() {
-	player.lock.Lock()
-	defer player.lock.Unlock()
+	if !player.isAlive() {
+		player.Unregister()
+		return
+	}
 
+	if player.server.GetSetup().EnableEnemies {
+		player.enemySpawnTick()
+	}
+}
+
+// false if the player has no citizen or town hall left
+func (player *Player) isAlive() bool {
 	hasCitizens := false
 	player.ForEachEntities(func(entity IEntity) (shouldStop bool) {
 		if unit, ok := entity.(IUnit); ok {
@@ -229,18 +486,16 @@ func (player *Player) Tick() {
 		return
 	})
 
+	// see GetBuildingPositions for why the tiles are read without the lock
+	player.lock.RLock()
+	townHalls := slices.Clone(player.townHalls)
+	player.lock.RUnlock()
+
 	hasTownhalls := false
-	for _, th := range player.townHalls {
+	for _, th := range townHalls {
 		hasTownhalls = hasTownhalls ||
 			player.server.Tilemap().GetTile(th).Kind == terrain.TileTownHall
 	}
 
-	if !hasCitizens || !hasTownhalls {
-		player.Unregister()
-		return
-	}
-
-	if player.server.GetSetup().EnableEnemies {
-		player.enemySpawnTick()
-	}
+	return hasCitizens && hasTownhalls
 }
@@ -2,6 +2,7 @@ package entities
 
 import (
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
 	. "github.com/heavenston/creeps_server/creeps_server/server"
 )
 
@@ -24,3 +25,39 @@ func (event *PlayerDespawnEvent) GetAABB() AABB {
 	// empty aabb = covers all map
 	return AABB{}
 }
+
+type PlayerMessageEvent struct {
+	ServerEventBase
+	Sender    *Player
+	Recipient *Player
+	Message   string
+}
+
+func (event *PlayerMessageEvent) GetAABB() AABB {
+	// empty aabb = covers all map
+	return AABB{}
+}
+
+type PlayerAchievementEvent struct {
+	ServerEventBase
+	Player      *Player
+	Achievement string
+}
+
+func (event *PlayerAchievementEvent) GetAABB() AABB {
+	// empty aabb = covers all map
+	return AABB{}
+}
+
+// emitted every time the resources of a registered player change
+type PlayerResourcesEvent struct {
+	ServerEventBase
+	Player *Player
+	// the resources right after the change
+	Resources model.Resources
+}
+
+func (event *PlayerResourcesEvent) GetAABB() AABB {
+	// empty aabb = covers all map
+	return AABB{}
+}
@@ -28,6 +28,13 @@ type Raid struct {
 	campPosition    Point
 	targetPosition  Point
 	lastRaiderSpawn int
+
+	// amount of raiders to spawn before the raid is finished
+	size           int
+	spawnedCount   int
+	raiderStrength int
+	// set when all its raiders died, see RaidFinishedEvent
+	defeated bool
 }
 
 func NewRaid(
@@ -39,7 +46,7 @@ func NewRaid(
 	raid.InitOwnedEntities()
 
 	raid.server = server
-	raid.id = uid.GenUid()
+	raid.id = server.GenUid()
 
 	raid.ownerPlayerId = ownerPlayerId
 
@@ -56,6 +63,15 @@ func (raid *Raid) GetId() uid.Uid {
 	return raid.id
 }
 
+func (raid *Raid) GetCampPosition() Point {
+	return raid.campPosition
+}
+
+// the position the raiders go to
+func (raid *Raid) GetTargetPosition() Point {
+	return raid.targetPosition
+}
+
 // for IEntity
 func (raid *Raid) GetAABB() AABB {
 	return AABB{
@@ -89,7 +105,7 @@ func (raid *Raid) Register() {
 		return
 	}
 
-	raid.campPosition = raid.server.FindSpawnPoint(player.GetSpawnPoint(), 1, func(p Point) bool {
+	campPosition, hasRoom := raid.server.FindSpawnPoint(player.GetSpawnPoint(), 1, func(p Point) bool {
 		found := false
 		raid.server.ForEachEntity(func(entity IEntity) (shouldStop bool) {
 			eplayer, ok := entity.(*Player)
@@ -102,6 +118,12 @@ func (raid *Raid) Register() {
 		})
 		return !found
 	})
+	if !hasRoom {
+		log.Warn().Any("raid_id", raid.id).Any("owner_player", raid.ownerPlayerId).
+			Msg("No room for the raid camp")
+		return
+	}
+	raid.campPosition = campPosition
 	raid.server.Tilemap().SetTile(raid.campPosition, terrain.Tile{
 		Kind:  terrain.TileRaiderCamp,
 		Value: 0,
@@ -109,12 +131,22 @@ func (raid *Raid) Register() {
 
 	raid.targetPosition = player.GetSpawnPoint()
 
+	difficulty := player.GetDifficulty()
+	raid.size = scaleAmount(raid.server.GetSetup().EnemyRaidSize, difficulty)
+	raid.raiderStrength = scaleAmount(1, difficulty)
+
 	raid.server.RegisterEntity(raid)
 	raid.registered.Store(true)
 
+	raid.server.Events().Emit(&RaidStartedEvent{
+		Raid: raid,
+	})
+
 	log.Info().Any("raid_id", raid.id).
 		Any("point", raid.campPosition).
 		Any("owner_player", raid.ownerPlayerId).
+		Int("size", raid.size).
+		Int("raider_strength", raid.raiderStrength).
 		Msg("Started raid")
 }
 
@@ -136,7 +168,13 @@ func (raid *Raid) Unregister() {
 	})
 
 	raid.server.RemoveEntity(raid.id)
-	raid.registered.Store(false)
+	// only once if unregistered twice (ex: by the gc and its tick)
+	if raid.registered.Swap(false) {
+		raid.server.Events().Emit(&RaidFinishedEvent{
+			Raid:     raid,
+			Defeated: raid.defeated,
+		})
+	}
 }
 
 func (raid *Raid) Tick() {
@@ -155,16 +193,29 @@ func (raid *Raid) Tick() {
 		return
 	}
 
+	if raid.spawnedCount >= raid.size {
+		if raid.OwnedEntityCount() == 0 {
+			log.Info().
+				Any("player_id", raid.ownerPlayerId).
+				Any("raid_id", raid.GetId()).
+				Msg("Raid finished (all raiders are dead)")
+			raid.defeated = true
+			raid.Unregister()
+		}
+		return
+	}
+
 	currentTick := raid.server.Ticker().GetTickNumber()
-	rate := raid.server.GetSetup().EnemyTickRate
+	rate := scaleTickRate(raid.server.GetSetup().EnemyTickRate, player.GetDifficulty())
 
 	if currentTick-raid.lastRaiderSpawn < rate {
 		return
 	}
 
 	raid.lastRaiderSpawn = currentTick
+	raid.spawnedCount++
 
-	raider := NewRaiderUnit(raid.server, raid.id, raid.targetPosition)
+	raider := NewRaiderUnit(raid.server, raid.id, raid.targetPosition, raid.raiderStrength)
 	raider.SetPosition(raid.campPosition)
 	raider.Register()
 }
@@ -0,0 +1,29 @@
+package entities
+
+import (
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	. "github.com/heavenston/creeps_server/creeps_server/server"
+)
+
+type RaidStartedEvent struct {
+	ServerEventBase
+	Raid *Raid
+}
+
+func (event *RaidStartedEvent) GetAABB() AABB {
+	return event.Raid.GetAABB()
+}
+
+// emitted when a raid is unregistered, either because all its raiders died,
+// its player died or it was killed by an admin
+type RaidFinishedEvent struct {
+	ServerEventBase
+	Raid *Raid
+	// true if it finished because all its raiders died while its player was
+	// alive
+	Defeated bool
+}
+
+func (event *RaidFinishedEvent) GetAABB() AABB {
+	return event.Raid.GetAABB()
+}
@@ -1,10 +1,13 @@
 package entities
 
 import (
+	"slices"
 	"sync"
 
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
 	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/pathfinding"
 	"github.com/heavenston/creeps_server/creeps_lib/terrain"
 	"github.com/heavenston/creeps_server/creeps_lib/uid"
 	. "github.com/heavenston/creeps_server/creeps_server/server"
@@ -17,16 +20,37 @@ type RaiderUnit struct {
 
 	owner uid.Uid
 
+	// initial target, used if the player has nothing left to destroy
 	target Point
+	// how many tiles the raider can raze (destroying the building and the units
+	// on it) before dying
+	strength int
+
+	// remaining points to go through to reach the current target
+	path []Point
+	// moves done since the last time the target was chosen
+	movesSinceRetarget int
 }
 
-func NewRaiderUnit(server *Server, owner uid.Uid, target Point) *RaiderUnit {
+// maximum amount of tiles explored by the pathfinding, if a target cannot be
+// found in this many tiles the raider gives up on it
+const raiderPathfindingLimit = 4096
+
+// after this many moves the raider looks again for the nearest target (as
+// units can move)
+const raiderRetargetInterval = 8
+
+// how many of the nearest targets are tried before giving up
+const raiderTargetTries = 3
+
+func NewRaiderUnit(server *Server, owner uid.Uid, target Point, strength int) *RaiderUnit {
 	raider := new(RaiderUnit)
 	raider.unitInit(server)
 	raider.this = raider
 
 	raider.target = target
 	raider.owner = owner
+	raider.strength = strength
 
 	return raider
 }
@@ -58,18 +82,19 @@ func (raider *RaiderUnit) StartAction(action *Action, onFinished func()) error {
 		model.OpCodeMoveUp,
 		model.OpCodeMoveLeft,
 		model.OpCodeMoveRight,
-	}, func() {
-		if onFinished != nil {
-			onFinished()
-		}
-		raider.Tick()
-	})
+	}, onFinished)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// for the actionFinisher interface, moves again right away instead of
+// waiting for the next tick
+func (raider *RaiderUnit) actionFinished() {
+	raider.Tick()
+}
+
 func (raider *RaiderUnit) Tick() {
 	raider.lock.Lock()
 	defer raider.lock.Unlock()
@@ -106,13 +131,18 @@ func (raider *RaiderUnit) Tick() {
 		_, isC := entity.(*CitizenUnit)
 		_, isT := entity.(*TurretUnit)
 		_, isB := entity.(*BomberBotUnit)
-		if isC || isT || isB {
+		if (isC || isT || isB) && entity.IsRegistered() {
 			foundAndDestroy = true
 			entity.Unregister()
 		}
 	}
 
+	// everything on the tile is destroyed at once, the strength is how many
+	// tiles it can do it on
 	if foundAndDestroy {
+		raider.strength--
+	}
+	if foundAndDestroy && raider.strength <= 0 {
 		raider.Unregister()
 		return
 	}
@@ -122,13 +152,29 @@ func (raider *RaiderUnit) Tick() {
 		return
 	}
 
-	if raider.target == position {
+	if len(raider.path) > 0 && raider.path[0] == position {
+		raider.path = raider.path[1:]
+		raider.movesSinceRetarget++
+	}
+
+	if len(raider.path) == 0 ||
+		raider.movesSinceRetarget >= raiderRetargetInterval ||
+		position.Dist(raider.path[0]) != 1 ||
+		!canWalkOn(raider, raider.path[0]) {
+		raider.retarget(owner, position)
+	}
+
+	if len(raider.path) == 0 {
+		log.Debug().
+			Str("raider_id", string(raider.id)).
+			Any("position", position).
+			Msg("RAIDER: No reachable target, giving up")
 		raider.Unregister()
 		return
 	}
 
 	newAction := new(Action)
-	newAction.OpCode = model.OpCodeFromMoveDirection(position.DirTowards(raider.target))
+	newAction.OpCode = model.OpCodeFromMoveDirection(raider.path[0].Sub(position))
 
 	err := raider.StartAction(newAction, nil)
 	if err != nil {
@@ -138,3 +184,49 @@ func (raider *RaiderUnit) Tick() {
 			Msg("[RAIDER] Could not start action")
 	}
 }
+
+// chooses the nearest reachable building or unit of the attacked player and
+// computes the path to it
+// the path is left empty if no target is reachable
+// must be called with the lock
+func (raider *RaiderUnit) retarget(owner IOwnerEntity, position Point) {
+	raider.path = nil
+	raider.movesSinceRetarget = 0
+
+	targets := make([]Point, 0)
+	if player, ok := raider.server.GetEntity(owner.GetOwner()).(*Player); ok {
+		targets = append(targets, player.GetBuildingPositions()...)
+		player.ForEachEntities(func(entity IEntity) (shouldStop bool) {
+			if unit, ok := entity.(IUnit); ok {
+				targets = append(targets, unit.GetPosition())
+			}
+			return
+		})
+	}
+
+	slices.SortFunc(targets, func(a, b Point) int {
+		if d := position.Dist(a) - position.Dist(b); d != 0 {
+			return d
+		}
+		// unit positions come in a random order
+		return ComparePoints(a, b)
+	})
+	targets = targets[:mathutils.Min(len(targets), raiderTargetTries)]
+	// fallback if there is nothing reachable
+	targets = append(targets, raider.target)
+
+	walkable := func(p Point) bool {
+		return canWalkOn(raider, p)
+	}
+
+	for _, target := range targets {
+		if target == position {
+			continue
+		}
+		path, found := pathfinding.FindPath(position, target, walkable, raiderPathfindingLimit)
+		if found {
+			raider.path = path
+			return
+		}
+	}
+}
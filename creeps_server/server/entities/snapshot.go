@@ -0,0 +1,239 @@
+package entities
+
+import (
+	"fmt"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	. "github.com/heavenston/creeps_server/creeps_server/server"
+)
+
+// serializable states of the entities, see the snapshot package
+// restore functions register the entity, so the owner of an entity must be
+// restored before it
+
+type BuildingSnapshot struct {
+	Position Point            `json:"position"`
+	Kind     terrain.TileKind `json:"kind"`
+}
+
+type PlayerSnapshot struct {
+	Id                 uid.Uid            `json:"id"`
+	Username           string             `json:"username"`
+	Addr               string             `json:"addr"`
+	Token              string             `json:"token,omitempty"`
+	SpawnPoint         Point              `json:"spawnPoint"`
+	Resources          model.Resources    `json:"resources"`
+	TownHalls          []Point            `json:"townHalls"`
+	Buildings          []BuildingSnapshot `json:"buildings"`
+	Mailbox            []model.Message    `json:"mailbox"`
+	Misses             int                `json:"misses"`
+	Achievements       []string           `json:"achievements"`
+	RefinedCopper      int                `json:"refinedCopper"`
+	LastEnemySpawnTick int                `json:"lastEnemySpawnTick"`
+}
+
+type RaidSnapshot struct {
+	Id              uid.Uid `json:"id"`
+	OwnerPlayerId   uid.Uid `json:"ownerPlayerId"`
+	CampPosition    Point   `json:"campPosition"`
+	TargetPosition  Point   `json:"targetPosition"`
+	LastRaiderSpawn int     `json:"lastRaiderSpawn"`
+	Size            int     `json:"size"`
+	SpawnedCount    int     `json:"spawnedCount"`
+	RaiderStrength  int     `json:"raiderStrength"`
+}
+
+type UnitSnapshot struct {
+	OpCode     string          `json:"opcode"`
+	Id         uid.Uid         `json:"id"`
+	Owner      uid.Uid         `json:"owner"`
+	SpawnTick  int             `json:"spawnTick"`
+	Position   Point           `json:"position"`
+	Upgraded   bool            `json:"upgraded"`
+	Misses     int             `json:"misses"`
+	Inventory  model.Resources `json:"inventory"`
+	LastAction *ActionSnapshot `json:"lastAction,omitempty"`
+
+	// citizens only
+	LastEatenAt int `json:"lastEatenAt,omitempty"`
+	// raiders only
+	Target   Point `json:"target,omitempty"`
+	Strength int   `json:"strength,omitempty"`
+}
+
+func (player *Player) Snapshot() PlayerSnapshot {
+	player.lock.RLock()
+	defer player.lock.RUnlock()
+
+	snapshot := PlayerSnapshot{
+		Id:                 player.id,
+		Username:           player.username,
+		Addr:               player.addr,
+		Token:              player.token,
+		SpawnPoint:         player.spawnPoint,
+		Resources:          player.resources,
+		TownHalls:          append([]Point(nil), player.townHalls...),
+		Buildings:          make([]BuildingSnapshot, 0, len(player.buildings)),
+		Mailbox:            append([]model.Message(nil), player.mailbox...),
+		Misses:             player.misses,
+		Achievements:       append([]string(nil), player.achievements...),
+		RefinedCopper:      player.refinedCopper,
+		LastEnemySpawnTick: player.lastEnemySpawnTick,
+	}
+	for p, kind := range player.buildings {
+		snapshot.Buildings = append(snapshot.Buildings, BuildingSnapshot{
+			Position: p,
+			Kind:     kind,
+		})
+	}
+
+	return snapshot
+}
+
+func RestorePlayer(server *Server, snapshot PlayerSnapshot) *Player {
+	player := NewPlayer(server, snapshot.Username, snapshot.Addr, snapshot.SpawnPoint)
+
+	player.id = snapshot.Id
+	player.token = snapshot.Token
+	player.resources = snapshot.Resources
+	player.townHalls = snapshot.TownHalls
+	for _, building := range snapshot.Buildings {
+		player.buildings[building.Position] = building.Kind
+	}
+	player.mailbox = snapshot.Mailbox
+	player.misses = snapshot.Misses
+	player.achievements = snapshot.Achievements
+	player.refinedCopper = snapshot.RefinedCopper
+	player.lastEnemySpawnTick = snapshot.LastEnemySpawnTick
+
+	player.Register()
+	return player
+}
+
+func (raid *Raid) Snapshot() RaidSnapshot {
+	raid.tickLock.Lock()
+	defer raid.tickLock.Unlock()
+
+	return RaidSnapshot{
+		Id:              raid.id,
+		OwnerPlayerId:   raid.ownerPlayerId,
+		CampPosition:    raid.campPosition,
+		TargetPosition:  raid.targetPosition,
+		LastRaiderSpawn: raid.lastRaiderSpawn,
+		Size:            raid.size,
+		SpawnedCount:    raid.spawnedCount,
+		RaiderStrength:  raid.raiderStrength,
+	}
+}
+
+// unlike Register the camp is not searched again and no RaidStartedEvent
+// is emitted
+func RestoreRaid(server *Server, snapshot RaidSnapshot) *Raid {
+	raid := NewRaid(server, snapshot.OwnerPlayerId)
+
+	raid.id = snapshot.Id
+	raid.campPosition = snapshot.CampPosition
+	raid.targetPosition = snapshot.TargetPosition
+	raid.lastRaiderSpawn = snapshot.LastRaiderSpawn
+	raid.size = snapshot.Size
+	raid.spawnedCount = snapshot.SpawnedCount
+	raid.raiderStrength = snapshot.RaiderStrength
+
+	raid.server.RegisterEntity(raid)
+	raid.registered.Store(true)
+	return raid
+}
+
+func SnapshotUnit(iunit IUnit) (UnitSnapshot, error) {
+	extended, ok := iunit.(extendedUnit)
+	if !ok {
+		return UnitSnapshot{}, fmt.Errorf("unknown unit type %T", iunit)
+	}
+	unit := extended.getUnit()
+
+	snapshot := UnitSnapshot{
+		OpCode:    iunit.GetOpCode(),
+		Id:        unit.id,
+		Owner:     iunit.GetOwner(),
+		SpawnTick: unit.spawnTick,
+		Position:  unit.GetPosition(),
+		Upgraded:  unit.IsUpgraded(),
+		Misses:    unit.GetMisses(),
+		Inventory: unit.GetInventory(),
+	}
+
+	if action := unit.GetLastAction(); action != nil {
+		actionSnapshot, err := action.Snapshot()
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.LastAction = &actionSnapshot
+	}
+
+	switch u := iunit.(type) {
+	case *CitizenUnit:
+		u.lock.Lock()
+		snapshot.LastEatenAt = u.lastEatenAt
+		u.lock.Unlock()
+	case *RaiderUnit:
+		u.lock.RLock()
+		snapshot.Target = u.target
+		snapshot.Strength = u.strength
+		u.lock.RUnlock()
+	}
+
+	return snapshot, nil
+}
+
+// registers the unit and resumes its last action if it wasn't finished
+// the onFinished given to StartAction is not saved (the api and raiders give
+// none), what units do when their actions finish is kept with
+// actionFinisher
+func RestoreUnit(server *Server, snapshot UnitSnapshot) (IUnit, error) {
+	var restored extendedUnit
+	switch snapshot.OpCode {
+	case "citizen":
+		citizen := NewCitizenUnit(server, snapshot.Owner)
+		citizen.lastEatenAt = snapshot.LastEatenAt
+		restored = citizen
+	case "turret":
+		restored = NewTurretUnit(server, snapshot.Owner)
+	case "bomber-bot":
+		restored = NewBomberBotUnit(server, snapshot.Owner)
+	case "raider":
+		restored = NewRaiderUnit(server, snapshot.Owner, snapshot.Target, snapshot.Strength)
+	default:
+		return nil, fmt.Errorf("unknown unit opcode '%s'", snapshot.OpCode)
+	}
+
+	var action *Action
+	if snapshot.LastAction != nil {
+		var err error
+		action, err = snapshot.LastAction.Restore()
+		if err != nil {
+			return nil, fmt.Errorf("unit %s: %w", snapshot.Id, err)
+		}
+	}
+
+	unit := restored.getUnit()
+	unit.id = snapshot.Id
+	unit.spawnTick = snapshot.SpawnTick
+	unit.position.Store(snapshot.Position)
+	unit.upgraded.Store(snapshot.Upgraded)
+	unit.misses.Store(int32(snapshot.Misses))
+	unit.inventory = snapshot.Inventory
+
+	restored.Register()
+
+	if action != nil {
+		unit.lastAction.Store(action)
+		if !action.Finised.Load() {
+			unit.scheduleAction(action, action.FinishedAtTick, nil, nil)
+		}
+	}
+
+	return restored, nil
+}
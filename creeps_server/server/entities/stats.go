@@ -0,0 +1,59 @@
+package entities
+
+// gameplay values of a unit, see unitStats
+type UnitStats struct {
+	// side of the square observed around the unit
+	ObserveDistance int
+	// food eaten every setup.CitizenFeedingRate ticks
+	FeedAmount int
+	// added to setup.MaxLoad to get the inventory capacity of the unit
+	ExtraLoad int
+	// maximum distance (on both axis) of the unit's fire target
+	FireRange int
+	// cube "radius" of the fire's explosion around the target
+	// (0 only hits the target, 1 means a 3x3 area)
+	BlastRadius int
+}
+
+type upgradableStats struct {
+	Base     UnitStats
+	Upgraded UnitStats
+}
+
+// stats of each unit kind by opcode, before and after being upgraded
+var unitStats = map[string]upgradableStats{
+	"citizen": {
+		Base: UnitStats{
+			ObserveDistance: 6,
+			FeedAmount:      1,
+		},
+		Upgraded: UnitStats{
+			ObserveDistance: 7,
+			FeedAmount:      2,
+			ExtraLoad:       10,
+		},
+	},
+	"turret": {
+		Base: UnitStats{
+			ObserveDistance: 5,
+			FireRange:       5,
+		},
+		Upgraded: UnitStats{
+			ObserveDistance: 7,
+			FireRange:       7,
+		},
+	},
+	"bomber-bot": {
+		Base: UnitStats{
+			ObserveDistance: 5,
+			FireRange:       5,
+			BlastRadius:     1,
+		},
+		Upgraded: UnitStats{
+			ObserveDistance: 7,
+			FireRange:       7,
+			BlastRadius:     2,
+		},
+	},
+	"raider": {},
+}
@@ -39,14 +39,6 @@ func (turret *TurretUnit) GetOwner() uid.Uid {
 	return turret.owner
 }
 
-func (turret *TurretUnit) ObserveDistance() int {
-	if turret.IsUpgraded() {
-		// FIXME: Is it correct ?
-		return 7
-	}
-	return 5
-}
-
 func (turret *TurretUnit) StartAction(action *Action, onFinished func()) error {
 	err := turret.startAction(action, []model.ActionOpCode{
 		model.OpCodeUpgrade,
@@ -3,7 +3,6 @@ package entities
 import (
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/heavenston/creeps_server/creeps_lib/events"
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
@@ -20,17 +19,27 @@ type extendedUnit interface {
 	getUnit() *unit
 }
 
+// implemented by units that do something every time one of their actions
+// finishes, unlike the onFinished of StartAction it is also called for
+// actions restored from a snapshot
+type actionFinisher interface {
+	actionFinished()
+}
+
 // See server.go's IUnit interface to explain its functions
 type unit struct {
 	this IUnit
 	// read-only (no lock)
 	server *Server
 	// read-only (no lock)
-	id            uid.Uid
+	id uid.Uid
+	// read-only (no lock)
+	spawnTick     int
 	registered    atomic.Bool
 	position      AtomicPoint
 	lastAction    atomic.Pointer[Action]
 	upgraded      atomic.Bool
+	misses        atomic.Int32
 	inventoryLock sync.RWMutex
 	inventory     model.Resources
 	movedEvents   events.EventProvider[spatialmap.ObjectMovedEvent]
@@ -38,7 +47,8 @@ type unit struct {
 
 func (unit *unit) unitInit(server *Server) {
 	unit.server = server
-	unit.id = uid.GenUid()
+	unit.id = server.GenUid()
+	unit.spawnTick = server.Ticker().GetTickNumber()
 	unit.registered.Store(true)
 }
 
@@ -50,6 +60,10 @@ func (unit *unit) GetId() uid.Uid {
 	return unit.id
 }
 
+func (unit *unit) GetSpawnTick() int {
+	return unit.spawnTick
+}
+
 func (unit *unit) IsBusy() bool {
 	action := unit.GetLastAction()
 	return !unit.IsRegistered() || (action != nil && !action.Finised.Load())
@@ -120,10 +134,33 @@ func (unit *unit) SetUpgraded() {
 	if unit.server == nil {
 		return
 	}
+
+	unit.server.Events().Emit(&server.UnitUpgradedEvent{
+		Unit: unit.this,
+		Pos:  unit.GetPosition(),
+	})
+}
+
+// returns the stats of the unit depending on its kind and wether it is
+// upgraded
+func (unit *unit) GetStats() UnitStats {
+	stats := unitStats[unit.this.GetOpCode()]
+	if unit.IsUpgraded() {
+		return stats.Upgraded
+	}
+	return stats.Base
 }
 
 func (unit *unit) ObserveDistance() int {
-	return 0
+	return unit.GetStats().ObserveDistance
+}
+
+func (unit *unit) AddMiss() int {
+	return int(unit.misses.Add(1))
+}
+
+func (unit *unit) GetMisses() int {
+	return int(unit.misses.Load())
 }
 
 func (unit *unit) GetInventory() model.Resources {
@@ -202,42 +239,76 @@ func (unit *unit) startAction(
 	// }
 
 	action.StartedAtTick = unit.server.Ticker().GetTickNumber()
-	unit.lastAction.Store(action)
-
-	unit.server.Events().Emit(&UnitStartedActionEvent{
-		Unit:   unit.this,
-		Pos:    unit.GetPosition(),
-		Action: action,
-	})
-
-	go (func() {
-		costs := action.OpCode.GetCost(unit.server.GetCosts(), unit.this.GetUpgradeCosts())
-
-		<-time.After(unit.server.Ticker().TickDuration() * time.Duration(costs.Cast))
-		if !unit.IsRegistered() {
-			return
-		}
-
-		action.Finised.Store(true)
-
-		report := ApplyAction(action, unit.this)
-		if len(report.GetReport().ReportId) > 0 {
-			unit.GetServer().AddReport(report)
-		}
+	if action.OpCode.MoveDirection() != (Point{}) {
+		action.Cast = moveCast(unit.this, action.OpCode)
+	} else {
+		action.Cast = action.OpCode.GetCost(unit.server.GetCosts(), unit.this.GetUpgradeCosts()).Cast
+	}
+	position := unit.GetPosition()
+
+	unit.scheduleAction(
+		action,
+		action.StartedAtTick+action.Cast,
+		onFinished,
+		func(tick int) {
+			// the action can only be applied once this returns, so it is
+			// complete before anyone sees it and started is emitted before
+			// finished
+			action.FinishedAtTick = tick
+			unit.lastAction.Store(action)
+
+			unit.server.Events().Emit(&UnitStartedActionEvent{
+				Unit:   unit.this,
+				Pos:    position,
+				Action: action,
+			})
+		},
+	)
 
-		unit.server.Events().Emit(&UnitFinishedActionEvent{
-			Unit:   unit.this,
-			Pos:    unit.GetPosition(),
-			Action: action,
-			Report: report,
-		})
+	return nil
+}
 
-		if onFinished != nil {
-			onFinished()
-		}
-	})()
+// schedules the resolution of the action at the given tick and returns the
+// tick at which it will actually happen, see Ticker.Reschedule for
+// beforeQueued
+func (unit *unit) scheduleAction(
+	action *Action,
+	tick int,
+	onFinished func(),
+	beforeQueued func(tick int),
+) int {
+	return unit.server.Ticker().Reschedule(
+		action.StartedAtTick,
+		tick,
+		string(unit.id),
+		func() {
+			if !unit.IsRegistered() {
+				return
+			}
+
+			action.Finised.Store(true)
+
+			report := ApplyAction(action, unit.this)
+			if len(report.GetReport().ReportId) > 0 {
+				unit.GetServer().AddReport(report)
+			}
+
+			unit.server.Events().Emit(&UnitFinishedActionEvent{
+				Unit:   unit.this,
+				Pos:    unit.GetPosition(),
+				Action: action,
+				Report: report,
+			})
 
-	return nil
+			if onFinished != nil {
+				onFinished()
+			}
+			if finisher, ok := unit.this.(actionFinisher); ok {
+				finisher.actionFinished()
+			}
+		},
+		beforeQueued,
+	)
 }
 
 func (unit *unit) Register() {
@@ -0,0 +1,103 @@
+package entities
+
+import (
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	. "github.com/heavenston/creeps_server/creeps_server/server"
+)
+
+type walkRule uint8
+
+const (
+	// default value, so tiles not in the table cannot be walked on
+	cannotWalk walkRule = iota
+	canWalk
+	// only units of the player who built the building can walk on it
+	ownerCanWalk
+)
+
+// tiles that can be walked on by unit opcode
+var walkability = map[string]map[terrain.TileKind]walkRule{
+	"citizen": {
+		terrain.TileGrass: canWalk,
+		terrain.TileTree:  canWalk,
+		terrain.TileBush:  canWalk,
+		terrain.TileOil:   canWalk,
+		terrain.TileRoad:  canWalk,
+		// rock is gathered from the tile the unit is on
+		terrain.TileStone: canWalk,
+
+		terrain.TileTownHall:  ownerCanWalk,
+		terrain.TileHousehold: ownerCanWalk,
+		terrain.TileSmeltery:  ownerCanWalk,
+		terrain.TileSawMill:   ownerCanWalk,
+	},
+	"bomber-bot": {
+		terrain.TileGrass: canWalk,
+		terrain.TileTree:  canWalk,
+		terrain.TileBush:  canWalk,
+		terrain.TileOil:   canWalk,
+		terrain.TileRoad:  canWalk,
+		// rock is gathered from the tile the unit is on
+		terrain.TileStone: canWalk,
+
+		terrain.TileTownHall:  ownerCanWalk,
+		terrain.TileHousehold: ownerCanWalk,
+		terrain.TileSmeltery:  ownerCanWalk,
+		terrain.TileSawMill:   ownerCanWalk,
+	},
+	// raiders walks on every buildings to destroy them
+	"raider": {
+		terrain.TileGrass:        canWalk,
+		terrain.TileTree:         canWalk,
+		terrain.TileBush:         canWalk,
+		terrain.TileOil:          canWalk,
+		terrain.TileRoad:         canWalk,
+		terrain.TileRaiderCamp:   canWalk,
+		terrain.TileRaiderBorder: canWalk,
+
+		terrain.TileTownHall:  canWalk,
+		terrain.TileHousehold: canWalk,
+		terrain.TileSmeltery:  canWalk,
+		terrain.TileSawMill:   canWalk,
+	},
+}
+
+// returns true if the given unit is allowed to walk on the tile at the given
+// position (only looks at the terrain, see tileIsFull for units)
+func canWalkOn(unit IUnit, p Point) bool {
+	tile := unit.GetServer().Tilemap().GetTile(p)
+
+	switch walkability[unit.GetOpCode()][tile.Kind] {
+	case canWalk:
+		return true
+	case ownerCanWalk:
+		player, ok := unit.GetServer().GetEntity(unit.GetOwner()).(*Player)
+		return ok && player.HasBuilding(p)
+	}
+	return false
+}
+
+// returns true if the tile at the given position already has
+// setup.MaxUnitsPerTile units on it (raiders are not counted)
+// always false if the setup has no limit
+func tileIsFull(server *Server, p Point) bool {
+	limit := server.GetSetup().MaxUnitsPerTile
+	if limit <= 0 {
+		return false
+	}
+
+	count := 0
+	for _, entity := range server.Entities().GetAllIntersects(AABB{
+		From: p,
+		Size: Point{X: 1, Y: 1},
+	}) {
+		if _, isRaider := entity.(*RaiderUnit); isRaider {
+			continue
+		}
+		if _, isUnit := entity.(IUnit); isUnit {
+			count++
+		}
+	}
+	return count >= limit
+}
@@ -0,0 +1,70 @@
+package entities_test
+
+import (
+	"testing"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+func applyAction(unit server.IUnit, opcode model.ActionOpCode) model.IReport {
+	return entities.ApplyAction(&server.Action{
+		OpCode:   opcode,
+		ReportId: uid.GenUid(),
+	}, unit)
+}
+
+// a citizen moves right from its household to the tested tile and gathers
+func TestMoveAndGather(t *testing.T) {
+	tests := []struct {
+		name string
+		kind terrain.TileKind
+		// empty if the citizen cannot walk on the tile
+		resource model.ResourceKind
+	}{
+		{name: "stone", kind: terrain.TileStone, resource: model.Rock},
+		{name: "tree", kind: terrain.TileTree, resource: model.Wood},
+		{name: "bush", kind: terrain.TileBush, resource: model.Food},
+		{name: "oil", kind: terrain.TileOil, resource: model.Oil},
+		{name: "water", kind: terrain.TileWater},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := servertest.NewServer(0)
+			_, citizen, _ := servertest.SpawnPlayer(srv, "alice", Point{})
+			target := citizen.GetPosition().Plus(1, 0)
+			srv.Tilemap().SetTile(target, terrain.Tile{Kind: test.kind, Value: 10})
+
+			report := applyAction(citizen, model.OpCodeMoveRight)
+			if test.resource == "" {
+				errorReport, ok := report.(*model.ErrorReport)
+				if !ok || errorReport.ErrorCode != "tile-not-walkable" {
+					t.Fatalf("expected the move to fail, got %+v", report)
+				}
+				return
+			}
+			if _, ok := report.(*model.MoveReport); !ok {
+				t.Fatalf("expected the move to succeed, got %+v", report)
+			}
+
+			report = applyAction(citizen, model.OpCodeGather)
+			gather, ok := report.(*model.GatherReport)
+			if !ok {
+				t.Fatalf("expected a gather report, got %+v", report)
+			}
+			if gather.Resource != test.resource || gather.Gathered <= 0 {
+				t.Fatalf("expected to gather %s, got %+v", test.resource, gather)
+			}
+			inventory := citizen.GetInventory()
+			if got := *inventory.OfKind(test.resource); got != gather.Gathered {
+				t.Errorf("expected %d %s in the inventory, got %d", gather.Gathered, test.resource, got)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"cmp"
+	"slices"
 	"sync"
 
 	"github.com/heavenston/creeps_server/creeps_lib/events"
@@ -108,3 +110,11 @@ func (e *OwnerEntity) RemoveEntity(id uid.Uid) IEntity {
 func (f *OwnerEntity) OwnedEntityCount() int {
 	return len(f.ownedEntities)
 }
+
+// sorts the given entities by id, so iterating over them doesn't depend on
+// map ordering
+func SortEntities[T IEntity](entities []T) {
+	slices.SortFunc(entities, func(a, b T) int {
+		return cmp.Compare(a.GetId(), b.GetId())
+	})
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/rs/zerolog/log"
+)
+
+// Ran each tick, every GcTickRate ticks removes old reports and entities
+// whose owner doesn't exist anymore (like raids of dead players), whether
+// EnableGC is set or not, a GcTickRate of 0 disables everything
+// If EnableGC is set, "Hector" also kills units that have been idle for more
+// than GcUnitMaxIdle ticks (like epita's creeps), see idleCollectedUnits
+func (srv *Server) gcTick() {
+	rate := srv.setup.GcTickRate
+	if rate <= 0 {
+		return
+	}
+
+	currentTick := srv.ticker.GetTickNumber()
+	if currentTick-srv.lastGcTick < rate {
+		return
+	}
+	srv.lastGcTick = currentTick
+
+	reports := srv.collectReports(currentTick)
+	orphans := srv.collectOrphans()
+	idles := 0
+	if srv.setup.EnableGC {
+		idles = srv.collectIdleUnits(currentTick)
+	}
+
+	log.Debug().
+		Int("tick", currentTick).
+		Int("reports", reports).
+		Int("orphans", orphans).
+		Int("idle_units", idles).
+		Msg("[GC] Collected garbage")
+}
+
+// removes reports older than GcReportMaxAge ticks and returns how many were
+// removed
+func (srv *Server) collectReports(currentTick int) int {
+	maxAge := srv.setup.GcReportMaxAge
+	if maxAge <= 0 {
+		return 0
+	}
+
+	srv.reportsLock.Lock()
+	defer srv.reportsLock.Unlock()
+
+	count := 0
+	for id, stored := range srv.reports {
+		if currentTick-stored.addedAtTick > maxAge {
+			delete(srv.reports, id)
+			count++
+		}
+	}
+	return count
+}
+
+// unregisters all entities whose owner isn't registered anymore and returns
+// how many were removed
+func (srv *Server) collectOrphans() int {
+	orphans := make([]IEntity, 0)
+	srv.ForEachEntity(func(entity IEntity) (shouldStop bool) {
+		ownerId := entity.GetOwner()
+		if ownerId == uid.ServerUid {
+			return
+		}
+		if srv.entitiesMap[ownerId] == nil {
+			orphans = append(orphans, entity)
+		}
+		return
+	})
+
+	SortEntities(orphans)
+	// unregister outside of ForEachEntity as it needs the entities lock
+	for _, orphan := range orphans {
+		if !orphan.IsRegistered() {
+			continue
+		}
+		log.Debug().
+			Type("entity_type", orphan).
+			Str("entity_id", string(orphan.GetId())).
+			Str("owner_id", string(orphan.GetOwner())).
+			Msg("[GC] Removing orphan entity")
+		orphan.Unregister()
+	}
+	return len(orphans)
+}
+
+// opcodes of the units killed by Hector when idle, the others (turrets,
+// raiders) can wait for a long time by design
+var idleCollectedUnits = map[string]bool{
+	"citizen":    true,
+	"bomber-bot": true,
+}
+
+// kills all units not doing anything since GcUnitMaxIdle ticks and returns
+// how many were killed
+func (srv *Server) collectIdleUnits(currentTick int) int {
+	maxIdle := srv.setup.GcUnitMaxIdle
+	if maxIdle <= 0 {
+		return 0
+	}
+
+	idles := make([]IUnit, 0)
+	srv.ForEachEntity(func(entity IEntity) (shouldStop bool) {
+		unit, ok := entity.(IUnit)
+		if !ok || !idleCollectedUnits[unit.GetOpCode()] || unit.IsBusy() {
+			return
+		}
+
+		lastActive := unit.GetSpawnTick()
+		if action := unit.GetLastAction(); action != nil {
+			lastActive = action.StartedAtTick
+		}
+
+		if currentTick-lastActive > maxIdle {
+			idles = append(idles, unit)
+		}
+		return
+	})
+
+	SortEntities(idles)
+	for _, unit := range idles {
+		if !unit.IsRegistered() {
+			continue
+		}
+		log.Debug().
+			Str("unit_opcode", unit.GetOpCode()).
+			Str("unit_id", string(unit.GetId())).
+			Msg("[GC] Hector killed an idle unit")
+		unit.Unregister()
+	}
+	return len(idles)
+}
@@ -0,0 +1,85 @@
+package server_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+// runs the ticker until the given amount of ticks were done
+func runTicks(t *testing.T, srv *server.Server, ticks int) {
+	t.Helper()
+
+	ticker := srv.Ticker()
+	target := ticker.GetTickNumber() + ticks
+	ticker.Pause()
+	go ticker.Start()
+	ticker.Step(ticks)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ticker.GetTickNumber() < target {
+		if time.Now().After(deadline) {
+			t.Fatalf("the ticker did not reach the tick %d", target)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ticker.PauseAndWait()
+}
+
+func TestHectorKillsIdleUnits(t *testing.T) {
+	tests := []struct {
+		name string
+		// creates the unit of the player that stays idle
+		create func(srv *server.Server, player *entities.Player) server.IUnit
+		killed bool
+	}{
+		{
+			name: "citizen",
+			create: func(srv *server.Server, player *entities.Player) server.IUnit {
+				return entities.NewCitizenUnit(srv, player.GetId())
+			},
+			killed: true,
+		},
+		{
+			name: "bomber-bot",
+			create: func(srv *server.Server, player *entities.Player) server.IUnit {
+				return entities.NewBomberBotUnit(srv, player.GetId())
+			},
+			killed: true,
+		},
+		{
+			name: "turret",
+			create: func(srv *server.Server, player *entities.Player) server.IUnit {
+				return entities.NewTurretUnit(srv, player.GetId())
+			},
+			killed: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := servertest.NewServer(0)
+			setup := srv.GetSetup()
+			setup.EnableGC = true
+			setup.EnableEnemies = false
+			setup.GcTickRate = 1
+			setup.GcUnitMaxIdle = 10
+
+			player, _, _ := servertest.SpawnPlayer(srv, "alice", Point{})
+			unit := test.create(srv, player)
+			unit.SetPosition(Point{X: 3, Y: 3})
+			unit.Register()
+
+			srv.Ticker().SetTickNumber(setup.GcUnitMaxIdle + 1)
+			runTicks(t, srv, 1)
+
+			if killed := !unit.IsRegistered(); killed != test.killed {
+				t.Errorf("expected killed to be %v, got %v", test.killed, killed)
+			}
+		})
+	}
+}
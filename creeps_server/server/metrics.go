@@ -0,0 +1,111 @@
+package server
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
+)
+
+// Adds to the registry the metrics describing the state of the server,
+// computed when they are collected
+// must be called only once per registry
+func (srv *Server) RegisterMetrics(registry *metrics.Registry) {
+	registry.AddHistogram(
+		"creeps_tick_duration_seconds",
+		"Time taken by the tick functions of each tick",
+		srv.ticker.tickDuration,
+	)
+	registry.AddCounter(
+		"creeps_tick_overruns_total",
+		"Ticks that took longer than the duration of a tick",
+		&srv.ticker.tickOverruns,
+	)
+	registry.NewGaugeFunc(
+		"creeps_tick",
+		"Number of the current tick",
+		func() float64 { return float64(srv.ticker.GetTickNumber()) },
+	)
+	registry.NewGaugeFunc(
+		"creeps_ticks_per_second",
+		"Target speed of the ticker, 0 if it runs as fast as possible",
+		func() float64 { return srv.ticker.GetState().TicksPerSecond },
+	)
+	registry.NewGaugeFunc(
+		"creeps_ticker_paused",
+		"1 if the ticker is paused",
+		func() float64 { return boolValue(srv.ticker.GetState().Paused) },
+	)
+	registry.NewGaugeVecFunc(
+		"creeps_entities",
+		"Registered entities by type (the opcode for units)",
+		[]string{"type"},
+		func(set metrics.SetFunc) {
+			counts := make(map[string]int)
+			srv.ForEachEntity(func(entity IEntity) (shouldStop bool) {
+				counts[entityKind(entity)]++
+				return
+			})
+			kinds := make([]string, 0, len(counts))
+			for kind := range counts {
+				kinds = append(kinds, kind)
+			}
+			slices.Sort(kinds)
+			for _, kind := range kinds {
+				set(float64(counts[kind]), kind)
+			}
+		},
+	)
+	registry.NewGaugeFunc(
+		"creeps_players_alive",
+		"Registered players",
+		func() float64 {
+			count := 0
+			srv.ForEachEntity(func(entity IEntity) (shouldStop bool) {
+				if entityKind(entity) == "player" {
+					count++
+				}
+				return
+			})
+			return float64(count)
+		},
+	)
+	registry.NewGaugeFunc(
+		"creeps_reports",
+		"Reports stored and not yet garbage collected",
+		func() float64 { return float64(srv.GetReportCount()) },
+	)
+	registry.NewGaugeFunc(
+		"creeps_generated_chunks",
+		"Chunks of the tilemap that are generated",
+		func() float64 { return float64(len(srv.tilemap.GetGeneratedChunks())) },
+	)
+	registry.NewCounterFunc(
+		"creeps_dropped_events_total",
+		"Server and tilemap events not sent to a subscriber because its channel was full",
+		func() float64 {
+			return float64(srv.events.GetDroppedCount() + srv.tilemap.UpdatedEventProvider.GetDroppedCount())
+		},
+	)
+}
+
+// the opcode of units or the lowercase name of the type of other entities
+// (player, raid, ...)
+func entityKind(entity IEntity) string {
+	if unit, ok := entity.(IUnit); ok {
+		return unit.GetOpCode()
+	}
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return strings.ToLower(t.Name())
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
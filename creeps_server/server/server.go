@@ -7,6 +7,7 @@ import (
 
 	"github.com/heavenston/creeps_server/creeps_lib/events/spatialevents"
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
 	"github.com/heavenston/creeps_server/creeps_lib/model"
 	"github.com/heavenston/creeps_server/creeps_lib/spatialmap"
 	"github.com/heavenston/creeps_server/creeps_lib/terrain"
@@ -27,15 +28,27 @@ type Server struct {
 	entitiesSpatialmap *spatialmap.SpatialMap[IEntity]
 	entitiesMap        map[uid.Uid]IEntity
 
-	// reports older than (somevalue) gets removed by the creeps garbage collector
-	reports     map[uid.Uid]model.IReport
+	// reports older than setup.GcReportMaxAge gets removed by the creeps
+	// garbage collector (see gc.go)
+	reports     map[uid.Uid]storedReport
 	reportsLock sync.RWMutex
 
+	// only accessed from the ticker's goroutine
+	lastGcTick int
+
 	defaultPlayerResourcesLock sync.RWMutex
 	defaultPlayerResources     model.Resources
 
 	randLock  sync.Mutex
 	spawnRand rand.Rand
+
+	// see GenUid
+	uids uid.Generator
+}
+
+type storedReport struct {
+	report      model.IReport
+	addedAtTick int
 }
 
 func NewServer(tilemap *terrain.Tilemap, setup *model.SetupResponse, costs *model.CostsResponse) *Server {
@@ -43,16 +56,32 @@ func NewServer(tilemap *terrain.Tilemap, setup *model.SetupResponse, costs *mode
 	srv.tilemap = tilemap
 
 	srv.events = spatialevents.NewSpatialEventProvider[IServerEvent]()
+	srv.events.Stamp = func(event IServerEvent) IServerEvent {
+		event.setTick(srv.ticker.GetTickNumber())
+		return event
+	}
+	srv.tilemap.UpdatedEventProvider.Stamp = func(event terrain.TilemapChunkEvent) terrain.TilemapChunkEvent {
+		event.Tick = srv.ticker.GetTickNumber()
+		return event
+	}
 
 	srv.entitiesSpatialmap = spatialmap.NewSpatialMap[IEntity]()
 	srv.entitiesMap = make(map[uid.Uid]IEntity)
 
-	srv.reports = make(map[uid.Uid]model.IReport)
+	srv.reports = make(map[uid.Uid]storedReport)
 
 	srv.ticker = NewTicker(setup.TicksPerSecond)
 	srv.ticker.AddTickFunc(func() {
 		srv.tick()
 	})
+	srv.ticker.AddTickFunc(func() {
+		srv.gcTick()
+	})
+	srv.ticker.AddStateFunc(func(state TickerState) {
+		srv.events.Emit(&TickerStateEvent{
+			State: state,
+		})
+	})
 
 	srv.setup = setup
 	srv.costs = costs
@@ -67,6 +96,9 @@ func NewServer(tilemap *terrain.Tilemap, setup *model.SetupResponse, costs *mode
 			if !ok {
 				break
 			}
+			if _, ok := event.(*ServerStoppedEvent); ok {
+				break
+			}
 
 			log.Trace().
 				Type("event_type", event).
@@ -85,6 +117,7 @@ func (srv *Server) tick() {
 		entities = append(entities, entity)
 	}
 	srv.entitiesLock.Unlock()
+	SortEntities(entities)
 
 	for _, entity := range entities {
 		// can happen if a previously ticked entity killed it
@@ -271,23 +304,44 @@ func (srv *Server) AddReport(report model.IReport) {
 		panic("empty report id")
 	}
 
-	if srv.reports[report.GetReport().ReportId] != nil {
+	if _, exists := srv.reports[report.GetReport().ReportId]; exists {
 		panic(fmt.Errorf("cannot add a report twice (%s)", report.GetReport().ReportId))
 	}
 
-	srv.reports[report.GetReport().ReportId] = report
+	srv.reports[report.GetReport().ReportId] = storedReport{
+		report:      report,
+		addedAtTick: srv.ticker.GetTickNumber(),
+	}
+}
+
+// counts the reports that were not garbage collected yet
+func (srv *Server) GetReportCount() int {
+	srv.reportsLock.RLock()
+	defer srv.reportsLock.RUnlock()
+	return len(srv.reports)
 }
 
+// returns nil if the report doesn't exist (or was garbage collected)
 func (srv *Server) GetReport(id uid.Uid) model.IReport {
 	srv.reportsLock.Lock()
 	defer srv.reportsLock.Unlock()
-	return srv.reports[id]
+	return srv.reports[id].report
 }
 
+// the setup the server was created with, must not be modified, see
+// GetCurrentSetup for the one sent to clients
 func (srv *Server) GetSetup() *model.SetupResponse {
 	return srv.setup
 }
 
+// returns a copy of the setup with the current speed of the ticker, which
+// can change while running
+func (srv *Server) GetCurrentSetup() *model.SetupResponse {
+	setup := *srv.setup
+	setup.TicksPerSecond = srv.ticker.GetState().TicksPerSecond
+	return &setup
+}
+
 func (srv *Server) GetCosts() *model.CostsResponse {
 	return srv.costs
 }
@@ -297,6 +351,15 @@ func (srv *Server) Start() {
 	srv.ticker.Start()
 }
 
+// Stops the ticker, waiting for the current tick to end, and emits a
+// ServerStoppedEvent, the server cannot be started again
+// must not be called from the ticker's goroutine
+func (srv *Server) Stop() {
+	srv.ticker.Stop()
+	srv.events.Emit(&ServerStoppedEvent{})
+	log.Info().Msg("Server stopped")
+}
+
 func (srv *Server) SetDefaultPlayerResources(resources model.Resources) {
 	srv.defaultPlayerResourcesLock.Lock()
 	defer srv.defaultPlayerResourcesLock.Unlock()
@@ -367,32 +430,66 @@ func (srv *Server) findSpawnPointNear(from Point, freeAreaSide int) (Point, bool
 	}
 }
 
+// restarts the sequence of random spawn points with the given seed
+func (srv *Server) SeedSpawns(seed int64) {
+	srv.randLock.Lock()
+	defer srv.randLock.Unlock()
+	srv.spawnRand = *rand.New(rand.NewSource(seed))
+}
+
+// makes the uids generated by the server a reproducible sequence, without
+// changing the ones of other servers in the same process
+func (srv *Server) SeedUids(seed int64) {
+	srv.uids.Seed(seed)
+}
+
+// generates the uid of a new entity or report of the server, see SeedUids
+func (srv *Server) GenUid() uid.Uid {
+	return srv.uids.GenUid()
+}
+
 // Returns a safe spawn point with graas tile in the given cube "radius"
 // also only consider a point if filter returns true
+// returns false if no point could be found (can realisticly only happen
+// if the world is bounded)
 // reentry will deadlock but all other functions of server are available
-func (srv *Server) FindSpawnPoint(start Point, freeAreaSide int, filter func(p Point) bool) Point {
+func (srv *Server) FindSpawnPoint(start Point, freeAreaSide int, filter func(p Point) bool) (Point, bool) {
 	dist := 5
+	maxDist := 1_000_000_000
+
+	bounds := srv.setup.WorldBounds()
+	if srv.setup.BoundedWorld {
+		// enough to reach the whole world from anywhere inside of it
+		maxDist = mathutils.Max(bounds.Size.X, bounds.Size.Y, dist)
+	}
 
 	srv.randLock.Lock()
 	defer srv.randLock.Unlock()
 
 	log.Trace().Int("dist", dist).Msg("[SPAWN_POINT] Looking for spawn point...")
-	for dist < 1_000_000_000 {
+	for {
 		for try := 0; try < 120; try++ {
 			center := start.Add(Point{
 				X: srv.spawnRand.Intn(dist*2) - dist,
 				Y: srv.spawnRand.Intn(dist*2) - dist,
 			})
+			if srv.setup.BoundedWorld {
+				center = bounds.Clamp(center)
+			}
 			point, found := srv.findSpawnPointNear(center, 2)
 
-			if found && filter(point) {
+			if found && srv.setup.IsInWorld(point) && filter(point) {
 				log.Trace().Any("point", point).Msg("[SPAWN_POINT] Found")
-				return point
+				return point, true
 			}
 		}
+		if dist >= maxDist {
+			break
+		}
 		log.Trace().Int("dist", dist).Msg("[SPAWN_POINT] not found, increasing dist")
-		dist += dist / 2
+		dist = mathutils.Min(dist+dist/2, maxDist)
 	}
 
-	panic("could not find spawn point")
+	log.Warn().Any("start", start).Msg("[SPAWN_POINT] Could not find any spawn point")
+	return Point{}, false
 }
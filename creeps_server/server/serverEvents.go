@@ -10,16 +10,29 @@ import (
 
 // utility struct embedded into all server events to auto-implement the functions
 type ServerEventBase struct {
+	// set when emitted, see GetTick
+	tick int
 }
 
 func (event *ServerEventBase) MovementEvents() *events.EventProvider[spatialmap.ObjectMovedEvent] {
 	return nil
 }
 
+// the tick at which the event was emitted, subscribers may receive it later
+func (event *ServerEventBase) GetTick() int {
+	return event.tick
+}
+
+func (event *ServerEventBase) setTick(tick int) {
+	event.tick = tick
+}
+
 // see spatialmap.Spatialized
 type IServerEvent interface {
 	MovementEvents() *events.EventProvider[spatialmap.ObjectMovedEvent]
 	GetAABB() AABB
+	GetTick() int
+	setTick(tick int)
 }
 
 type UnitSpawnEvent struct {
@@ -98,3 +111,38 @@ func (event *UnitFinishedActionEvent) GetAABB() AABB {
 		Size: Point{X: 1, Y: 1},
 	}
 }
+
+type UnitUpgradedEvent struct {
+	ServerEventBase
+	Unit IUnit
+	Pos  Point
+}
+
+func (event *UnitUpgradedEvent) GetAABB() AABB {
+	return AABB{
+		From: event.Pos,
+		Size: Point{X: 1, Y: 1},
+	}
+}
+
+// emitted when the ticker is paused, resumed or its speed changes
+type TickerStateEvent struct {
+	ServerEventBase
+	State TickerState
+}
+
+// covers the whole map
+func (event *TickerStateEvent) GetAABB() AABB {
+	return AABB{}
+}
+
+// emitted once by Server.Stop after the last tick, nothing happens in the
+// server afterwards so subscribers can stop listening
+type ServerStoppedEvent struct {
+	ServerEventBase
+}
+
+// covers the whole map
+func (event *ServerStoppedEvent) GetAABB() AABB {
+	return AABB{}
+}
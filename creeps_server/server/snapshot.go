@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+)
+
+// serializable state of an Action, see the snapshot package
+type ActionSnapshot struct {
+	OpCode         model.ActionOpCode `json:"opcode"`
+	StartedAtTick  int                `json:"startedAtTick"`
+	Cast           int                `json:"cast"`
+	FinishedAtTick int                `json:"finishedAtTick"`
+	ReportId       uid.Uid            `json:"reportId"`
+	Finished       bool               `json:"finished"`
+	Parameter      json.RawMessage    `json:"parameter,omitempty"`
+}
+
+// serializable state of a stored report, see the snapshot package
+type ReportSnapshot struct {
+	Report json.RawMessage `json:"report"`
+	// error message of error reports, as it is not serialized with them
+	Error       string `json:"error,omitempty"`
+	AddedAtTick int    `json:"addedAtTick"`
+}
+
+func (action *Action) Snapshot() (ActionSnapshot, error) {
+	snapshot := ActionSnapshot{
+		OpCode:         action.OpCode,
+		StartedAtTick:  action.StartedAtTick,
+		Cast:           action.Cast,
+		FinishedAtTick: action.FinishedAtTick,
+		ReportId:       action.ReportId,
+		Finished:       action.Finised.Load(),
+	}
+
+	if action.Parameter != nil {
+		parameter, err := json.Marshal(action.Parameter)
+		if err != nil {
+			return snapshot, err
+		}
+		snapshot.Parameter = parameter
+	}
+
+	return snapshot, nil
+}
+
+// creates a new action with the saved state, it still has to be started or
+// resumed by its unit
+func (snapshot *ActionSnapshot) Restore() (*Action, error) {
+	if !snapshot.OpCode.IsValid() {
+		return nil, fmt.Errorf("invalid action opcode '%s'", snapshot.OpCode)
+	}
+
+	action := &Action{
+		OpCode:         snapshot.OpCode,
+		StartedAtTick:  snapshot.StartedAtTick,
+		Cast:           snapshot.Cast,
+		FinishedAtTick: snapshot.FinishedAtTick,
+		ReportId:       snapshot.ReportId,
+	}
+	action.Finised.Store(snapshot.Finished)
+
+	paramType := snapshot.OpCode.ParameterType()
+	if paramType != nil && len(snapshot.Parameter) > 0 {
+		paramValue := reflect.New(paramType)
+		err := json.Unmarshal(snapshot.Parameter, paramValue.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("action parameter: %w", err)
+		}
+		action.Parameter = reflect.Indirect(paramValue).Interface()
+	}
+
+	return action, nil
+}
+
+// saves every report that wasn't garbage collected yet
+func (srv *Server) SnapshotReports() ([]ReportSnapshot, error) {
+	srv.reportsLock.RLock()
+	defer srv.reportsLock.RUnlock()
+
+	snapshots := make([]ReportSnapshot, 0, len(srv.reports))
+	for _, stored := range srv.reports {
+		data, err := json.Marshal(stored.report)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot := ReportSnapshot{
+			Report:      data,
+			AddedAtTick: stored.addedAtTick,
+		}
+		if errorReport, ok := stored.report.(*model.ErrorReport); ok {
+			snapshot.Error = errorReport.Error
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// adds back the given saved reports, the type of each report is found back
+// with its opcode and status
+func (srv *Server) RestoreReports(snapshots []ReportSnapshot) error {
+	srv.reportsLock.Lock()
+	defer srv.reportsLock.Unlock()
+
+	for _, snapshot := range snapshots {
+		var base model.Report
+		err := json.Unmarshal(snapshot.Report, &base)
+		if err != nil {
+			return err
+		}
+		if !base.OpCode.IsValid() {
+			return fmt.Errorf("report %s has an invalid opcode '%s'", base.ReportId, base.OpCode)
+		}
+
+		var report model.IReport
+		if base.Status == "ERROR" {
+			report = &model.ErrorReport{
+				Error: snapshot.Error,
+			}
+		} else {
+			report = reflect.New(base.OpCode.GetReportType()).Interface().(model.IReport)
+		}
+
+		err = json.Unmarshal(snapshot.Report, report)
+		if err != nil {
+			return fmt.Errorf("report %s: %w", base.ReportId, err)
+		}
+
+		srv.reports[base.ReportId] = storedReport{
+			report:      report,
+			addedAtTick: snapshot.AddedAtTick,
+		}
+	}
+
+	return nil
+}
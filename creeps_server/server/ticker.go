@@ -1,20 +1,50 @@
 package server
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
 	"github.com/rs/zerolog/log"
 )
 
 type TickFunc func()
 
+// see Ticker.Schedule
+type scheduledFunc struct {
+	// tick at which Schedule was called
+	scheduledAt int
+	id          string
+	fun         TickFunc
+}
+
 type Ticker struct {
+	// held while a tick is running, see PauseAndWait
+	tickLock sync.Mutex
+
+	// guards ticksPerSecond, paused and pendingSteps
+	controlLock sync.Mutex
+	// 0 or less means as fast as possible
 	ticksPerSecond float64
+	paused         bool
+	// ticks that are still to be done while paused, see Step
+	pendingSteps int
+	// set by Stop, Start returns once it is set
+	stopped bool
+	// receives a value every time one of the above is changed
+	controlChanged chan struct{}
+
+	stateFuncsLock sync.RWMutex
+	// see AddStateFunc
+	stateFuncs []StateFunc
 
 	tickNumber atomic.Int32
 	startedAt  time.Time
+	// unix nano time at which the last tick finished, 0 if never ticked
+	lastTickAt atomic.Int64
 
 	tickFuncsLock sync.RWMutex
 	tickFuncs     []TickFunc
@@ -22,24 +52,45 @@ type Ticker struct {
 	deferedFuncsLock sync.RWMutex
 	// see ticker.Defer
 	deferedFuncs []TickFunc
+
+	// guards scheduledFuncs and nextScheduledTick
+	scheduledLock sync.Mutex
+	// see ticker.Schedule, indexed by the tick they must be called at
+	scheduledFuncs map[int][]scheduledFunc
+	// first tick which scheduled funcs have not been called yet
+	nextScheduledTick int
+
+	// see Server.RegisterMetrics
+	tickDuration *metrics.Histogram
+	tickOverruns metrics.Counter
 }
 
 func NewTicker(ticksPerSecond float64) *Ticker {
 	ticker := new(Ticker)
 	ticker.startedAt = time.Now()
 	ticker.ticksPerSecond = ticksPerSecond
+	ticker.controlChanged = make(chan struct{}, 1)
+	ticker.scheduledFuncs = make(map[int][]scheduledFunc)
+	ticker.tickDuration = metrics.NewHistogram(
+		[]float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	)
 	return ticker
 }
 
 func (ticker *Ticker) Start() {
-	log.Info().Float64("tps", ticker.ticksPerSecond).Msg("Ticker starting")
-	time_ticker := time.NewTicker(ticker.TickDuration())
-	defer time_ticker.Stop()
+	log.Info().Float64("tps", ticker.GetState().TicksPerSecond).Msg("Ticker starting")
 
 	for {
+		if !ticker.waitUnpaused() {
+			log.Info().Int("tick", ticker.GetTickNumber()).Msg("Ticker stopped")
+			return
+		}
+
 		start := time.Now()
 		log.Trace().Msg("Started tick")
 
+		ticker.runScheduled(ticker.GetTickNumber())
+
 		tickFuncs := make([]TickFunc, len(ticker.tickFuncs))
 		// copy to release the lock during the tick
 		ticker.tickFuncsLock.RLock()
@@ -61,8 +112,40 @@ func (ticker *Ticker) Start() {
 
 		log.Trace().TimeDiff("took", time.Now(), start).Msg("Finished tick")
 
-		_ = <-time_ticker.C
+		took := time.Since(start)
+		ticker.tickDuration.ObserveDuration(took)
+		if budget := ticker.TickDuration(); budget > 0 && took > budget {
+			ticker.tickOverruns.Inc()
+		}
+
+		ticker.lastTickAt.Store(time.Now().UnixNano())
+		// before releasing the lock so that, between ticks (see
+		// PauseAndWait), the tick number is the one of the next tick
 		ticker.tickNumber.Add(1)
+		// locked by waitUnpaused
+		ticker.tickLock.Unlock()
+
+		ticker.waitTickEnd(start)
+	}
+}
+
+// calls, in order, all functions scheduled for the given tick
+func (ticker *Ticker) runScheduled(tick int) {
+	ticker.scheduledLock.Lock()
+	scheduled := ticker.scheduledFuncs[tick]
+	delete(ticker.scheduledFuncs, tick)
+	ticker.nextScheduledTick = tick + 1
+	ticker.scheduledLock.Unlock()
+
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		if scheduled[i].scheduledAt != scheduled[j].scheduledAt {
+			return scheduled[i].scheduledAt < scheduled[j].scheduledAt
+		}
+		return scheduled[i].id < scheduled[j].id
+	})
+
+	for _, s := range scheduled {
+		s.fun()
 	}
 }
 
@@ -70,6 +153,43 @@ func (ticker *Ticker) GetTickNumber() int {
 	return int(ticker.tickNumber.Load())
 }
 
+// only meant to be used before Start, when restoring a saved game
+func (ticker *Ticker) SetTickNumber(tick int) {
+	ticker.scheduledLock.Lock()
+	defer ticker.scheduledLock.Unlock()
+
+	ticker.tickNumber.Store(int32(tick))
+	ticker.nextScheduledTick = tick
+}
+
+// returns true if the ticker is started, not paused and did not stall
+// (a tick finished in the last few tick durations, or second when running as
+// fast as possible)
+func (ticker *Ticker) IsRunning() bool {
+	last := ticker.lastTickAt.Load()
+	if last == 0 || ticker.GetState().Paused {
+		return false
+	}
+	return ticker.tickedSince(last)
+}
+
+// returns true if the ticker should be advancing but did not finish a tick
+// recently (see IsRunning), it is not stalled when paused or before the end
+// of its first tick
+func (ticker *Ticker) IsStalled() bool {
+	last := ticker.lastTickAt.Load()
+	if last == 0 || ticker.GetState().Paused {
+		return false
+	}
+	return !ticker.tickedSince(last)
+}
+
+// true if the given unix nano time is recent enough to not be considered
+// stalled
+func (ticker *Ticker) tickedSince(last int64) bool {
+	return time.Since(time.Unix(0, last)) < mathutils.Max(ticker.TickDuration()*10, time.Second)
+}
+
 func (ticker *Ticker) AddTickFunc(f TickFunc) {
 	ticker.tickFuncsLock.Lock()
 	defer ticker.tickFuncsLock.Unlock()
@@ -83,6 +203,49 @@ func (ticker *Ticker) Defer(f TickFunc) {
 	ticker.deferedFuncs = append(ticker.deferedFuncs, f)
 }
 
+// schedule the given function to be called at the start of the given tick,
+// before tick funcs, or of the next tick if its start already passed
+// returns the tick at which it will be called
+//
+// functions of the same tick are called ordered by the tick they were
+// scheduled at and then by id, so the order does not depend on timing
+func (ticker *Ticker) Schedule(tick int, id string, f TickFunc) int {
+	return ticker.Reschedule(ticker.GetTickNumber(), tick, id, f, nil)
+}
+
+// like Schedule but as if it was called at the given tick, used to restore
+// functions that were scheduled before a restart
+// if not nil, beforeQueued is called with the tick at which f will be called
+// before f can be called, so it can publish that tick safely (it must not
+// schedule anything itself)
+func (ticker *Ticker) Reschedule(
+	scheduledAt int,
+	tick int,
+	id string,
+	f TickFunc,
+	beforeQueued func(tick int),
+) int {
+	ticker.scheduledLock.Lock()
+	defer ticker.scheduledLock.Unlock()
+
+	tick = mathutils.Max(tick, ticker.nextScheduledTick)
+	if beforeQueued != nil {
+		beforeQueued(tick)
+	}
+	ticker.scheduledFuncs[tick] = append(ticker.scheduledFuncs[tick], scheduledFunc{
+		scheduledAt: scheduledAt,
+		id:          id,
+		fun:         f,
+	})
+	return tick
+}
+
+// 0 if the ticker runs as fast as possible
 func (ticker *Ticker) TickDuration() time.Duration {
+	ticker.controlLock.Lock()
+	defer ticker.controlLock.Unlock()
+	if ticker.ticksPerSecond <= 0 {
+		return 0
+	}
 	return time.Duration(float64(time.Second) / ticker.ticksPerSecond)
 }
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// see Ticker.GetState
+type TickerState struct {
+	// 0 means as fast as possible
+	TicksPerSecond float64
+	Paused         bool
+}
+
+// see Ticker.AddStateFunc
+type StateFunc func(state TickerState)
+
+func (ticker *Ticker) GetState() TickerState {
+	ticker.controlLock.Lock()
+	defer ticker.controlLock.Unlock()
+	return ticker.getState()
+}
+
+// must be called with the control lock
+func (ticker *Ticker) getState() TickerState {
+	return TickerState{
+		TicksPerSecond: ticker.ticksPerSecond,
+		Paused:         ticker.paused,
+	}
+}
+
+// Adds a function called with the new state every time the speed of the
+// ticker changes or it is paused/resumed
+func (ticker *Ticker) AddStateFunc(f StateFunc) {
+	ticker.stateFuncsLock.Lock()
+	defer ticker.stateFuncsLock.Unlock()
+	ticker.stateFuncs = append(ticker.stateFuncs, f)
+}
+
+// applies the given change with the control lock, wakes up the tick loop and
+// notifies the state funcs if the state changed
+func (ticker *Ticker) modifyControl(modify func()) {
+	ticker.controlLock.Lock()
+	previous := ticker.getState()
+	modify()
+	state := ticker.getState()
+	ticker.controlLock.Unlock()
+
+	select {
+	case ticker.controlChanged <- struct{}{}:
+	default:
+	}
+
+	if state == previous {
+		return
+	}
+
+	log.Info().Float64("tps", state.TicksPerSecond).
+		Bool("paused", state.Paused).
+		Msg("Ticker state changed")
+
+	ticker.stateFuncsLock.RLock()
+	defer ticker.stateFuncsLock.RUnlock()
+	for _, f := range ticker.stateFuncs {
+		f(state)
+	}
+}
+
+// Stops ticking after the current tick, until Resume is called
+func (ticker *Ticker) Pause() {
+	ticker.modifyControl(func() {
+		ticker.paused = true
+		ticker.pendingSteps = 0
+	})
+}
+
+func (ticker *Ticker) Resume() {
+	ticker.modifyControl(func() {
+		ticker.paused = false
+		ticker.pendingSteps = 0
+	})
+}
+
+// Pauses the ticker and then runs the given amount of ticks as fast as
+// possible, can be called again before they are done to add more
+func (ticker *Ticker) Step(ticks int) {
+	if ticks <= 0 {
+		return
+	}
+	ticker.modifyControl(func() {
+		if !ticker.paused {
+			ticker.paused = true
+			ticker.pendingSteps = 0
+		}
+		ticker.pendingSteps += ticks
+	})
+}
+
+// 0 or less to tick as fast as possible, takes effect on the current tick
+func (ticker *Ticker) SetTicksPerSecond(tps float64) {
+	if tps < 0 {
+		tps = 0
+	}
+	ticker.modifyControl(func() {
+		ticker.ticksPerSecond = tps
+	})
+}
+
+// Stops the ticker for good and waits for the current tick to finish, Start
+// returns and does nothing if called again
+// must not be called from the ticker's goroutine (it would deadlock)
+func (ticker *Ticker) Stop() {
+	ticker.modifyControl(func() {
+		ticker.stopped = true
+	})
+	ticker.tickLock.Lock()
+	defer ticker.tickLock.Unlock()
+}
+
+func (ticker *Ticker) IsStopped() bool {
+	ticker.controlLock.Lock()
+	defer ticker.controlLock.Unlock()
+	return ticker.stopped
+}
+
+// Pauses the ticker and waits for the current tick to finish, after which
+// nothing runs in the ticker until it is resumed
+// must not be called from the ticker's goroutine (it would deadlock)
+func (ticker *Ticker) PauseAndWait() {
+	ticker.Pause()
+	ticker.tickLock.Lock()
+	defer ticker.tickLock.Unlock()
+}
+
+// blocks while the ticker is paused and has no pending steps
+// (consumes one if any)
+// returns with the tick lock, so a pause cannot happen in between, or false
+// without it if the ticker is stopped
+func (ticker *Ticker) waitUnpaused() bool {
+	for {
+		ticker.controlLock.Lock()
+		if ticker.stopped {
+			ticker.controlLock.Unlock()
+			return false
+		}
+		if !ticker.paused {
+			ticker.tickLock.Lock()
+			ticker.controlLock.Unlock()
+			return true
+		}
+		if ticker.pendingSteps > 0 {
+			ticker.pendingSteps--
+			ticker.tickLock.Lock()
+			ticker.controlLock.Unlock()
+			return true
+		}
+		ticker.controlLock.Unlock()
+
+		<-ticker.controlChanged
+	}
+}
+
+// blocks until a tick duration passed since the given tick start
+// the duration is re-evaluated if the speed changes in the meantime and it
+// returns early if the ticker is paused (steps are done without waiting) or
+// stopped
+func (ticker *Ticker) waitTickEnd(start time.Time) {
+	for {
+		if ticker.GetState().Paused || ticker.IsStopped() {
+			return
+		}
+
+		remaining := time.Until(start.Add(ticker.TickDuration()))
+		if remaining <= 0 {
+			return
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+			return
+		case <-ticker.controlChanged:
+			timer.Stop()
+		}
+	}
+}
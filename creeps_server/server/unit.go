@@ -37,12 +37,19 @@ type IUnit interface {
 	// atomically modifies the position of the unit
 	ModifyPosition(cb func(Point) Point) (Point, Point)
 	GetLastAction() *Action
+	// the tick at which the unit was created
+	GetSpawnTick() int
 	// can return UnitBusyError or UnsuportedActionError
 	// unFinished can be nil
 	StartAction(action *Action, onFinished func()) error
 	GetUpgradeCosts() *model.CostResponse
 	IsUpgraded() bool
+	// emits UnitUpgradedEvent if the unit wasn't already upgraded
+	SetUpgraded()
 	ObserveDistance() int
+	// increments the unit's count of failed commands and returns the new count
+	AddMiss() int
+	GetMisses() int
 	GetInventory() model.Resources
 	// atomically modifier the inventory
 	ModifyInventory(func(model.Resources) model.Resources)
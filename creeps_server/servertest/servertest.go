@@ -0,0 +1,33 @@
+// helpers to create servers and players in tests
+package servertest
+
+import (
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_server/config"
+	"github.com/heavenston/creeps_server/creeps_server/gameplay"
+	"github.com/heavenston/creeps_server/creeps_server/generator"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+)
+
+// a server with the default config and a world generated from the seed, its
+// ticker is not started
+func NewServer(seed int64) *server.Server {
+	conf := config.Default()
+	tilemap := terrain.NewTilemap(generator.NewNoiseGenerator(seed))
+	return server.NewServer(&tilemap, &conf.Setup, &conf.Costs)
+}
+
+// registers a player like the epita api's init does, its town hall is at the
+// spawn point and its two citizens on the household right below
+func SpawnPlayer(
+	srv *server.Server,
+	username string,
+	spawnPoint Point,
+) (*entities.Player, *entities.CitizenUnit, *entities.CitizenUnit) {
+	player := entities.NewPlayer(srv, username, "127.0.0.1", spawnPoint)
+	player.SetResources(srv.GetDefaultPlayerResources())
+	_, _, c1, c2 := gameplay.InitPlayer(srv, player)
+	return player, c1, c2
+}
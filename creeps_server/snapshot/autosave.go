@@ -0,0 +1,111 @@
+package snapshot
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/rs/zerolog/log"
+)
+
+// Periodically saves a server to a file, see StartAutoSave
+type AutoSaver struct {
+	server        *server.Server
+	path          string
+	generatorSeed int64
+
+	// set while a save is being written to avoid piling them up
+	writing atomic.Bool
+	// serializes file writes
+	writeLock sync.Mutex
+}
+
+// Saves the server to the given path every interval ticks (never if 0 or
+// less, use Save for the shutdown save)
+// the state is taken at the end of a tick and written in another goroutine
+func StartAutoSave(
+	srv *server.Server,
+	path string,
+	generatorSeed int64,
+	interval int,
+) *AutoSaver {
+	saver := &AutoSaver{
+		server:        srv,
+		path:          path,
+		generatorSeed: generatorSeed,
+	}
+
+	if interval > 0 {
+		srv.Ticker().AddTickFunc(func() {
+			tick := srv.Ticker().GetTickNumber()
+			if tick == 0 || tick%interval != 0 {
+				return
+			}
+			// after entities ticked
+			srv.Ticker().Defer(saver.periodicSave)
+		})
+	}
+
+	log.Info().Str("path", path).Int("interval", interval).Msg("Auto save enabled")
+
+	return saver
+}
+
+// called from the ticker
+func (saver *AutoSaver) periodicSave() {
+	if saver.writing.Load() {
+		log.Warn().Str("path", saver.path).
+			Msg("Previous save is still being written, skipping this one")
+		return
+	}
+
+	snapshot, err := Take(saver.server, saver.generatorSeed)
+	if err != nil {
+		log.Error().Err(err).Msg("Could not take snapshot")
+		return
+	}
+	// deferred to the end of the tick, which must not be replayed when
+	// restored
+	snapshot.Tick++
+
+	saver.writing.Store(true)
+	go (func() {
+		defer saver.writing.Store(false)
+		saver.write(snapshot)
+	})()
+}
+
+func (saver *AutoSaver) write(snapshot *Snapshot) error {
+	saver.writeLock.Lock()
+	defer saver.writeLock.Unlock()
+
+	err := snapshot.WriteFile(saver.path)
+	if err != nil {
+		log.Error().Err(err).Str("path", saver.path).Msg("Could not save snapshot")
+		return err
+	}
+	log.Debug().Str("path", saver.path).Int("tick", snapshot.Tick).Msg("Saved snapshot")
+	return nil
+}
+
+// Pauses the ticker and saves the server synchronously, meant to be used on
+// shutdown (the ticker is not resumed)
+// once paused the ticker is between two ticks so the snapshot's tick is the
+// next one to run
+// must not be called from the ticker's goroutine
+func (saver *AutoSaver) Save() error {
+	saver.server.Ticker().PauseAndWait()
+
+	snapshot, err := Take(saver.server, saver.generatorSeed)
+	if err != nil {
+		log.Error().Err(err).Msg("Could not take snapshot")
+		return err
+	}
+	err = saver.write(snapshot)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("path", saver.path).Int("tick", snapshot.Tick).Msg("Saved snapshot")
+	return nil
+}
@@ -0,0 +1,70 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+// the tick of a save must be the next one to run, so restoring it does not
+// replay a tick
+func TestAutoSaveTick(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval int
+		// ticks run before the save
+		ticks int
+		// saves with Save instead of waiting for the periodic one
+		shutdown bool
+	}{
+		{name: "periodic", interval: 2, ticks: 3},
+		{name: "shutdown", interval: 0, ticks: 3, shutdown: true},
+		{name: "shutdown after a periodic save", interval: 2, ticks: 5, shutdown: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := servertest.NewServer(testSeed)
+			srv.Ticker().Pause()
+			path := filepath.Join(t.TempDir(), "save.json.gz")
+			saver := StartAutoSave(srv, path, testSeed, test.interval)
+
+			go srv.Ticker().Start()
+			defer srv.Ticker().Stop()
+			srv.Ticker().Step(test.ticks)
+
+			deadline := time.Now().Add(5 * time.Second)
+			for srv.Ticker().GetTickNumber() < test.ticks {
+				if time.Now().After(deadline) {
+					t.Fatalf("the ticker did not run %d ticks", test.ticks)
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			expected := test.ticks
+			if test.shutdown {
+				err := saver.Save()
+				if err != nil {
+					t.Fatalf("save: %v", err)
+				}
+			} else {
+				// the last periodic save, taken at the end of its tick
+				expected = test.ticks - test.ticks%test.interval + 1
+			}
+
+			var saved *Snapshot
+			for saved == nil {
+				if time.Now().After(deadline) {
+					t.Fatalf("nothing was saved")
+				}
+				saved, _ = ReadFile(path)
+				time.Sleep(time.Millisecond)
+			}
+			if saved.Tick != expected {
+				t.Errorf("expected the tick %d, got %d", expected, saved.Tick)
+			}
+		})
+	}
+}
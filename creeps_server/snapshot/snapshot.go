@@ -0,0 +1,201 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/rs/zerolog/log"
+)
+
+// incremented every time the format changes in an incompatible way
+const Version = 1
+
+type ChunkSnapshot struct {
+	ChunkPos Point `json:"chunkPos"`
+	// each tile has two bytes, one for the kind and one for its value
+	// encoded in row-major order (same as the viewer's fullChunk)
+	Tiles []byte `json:"tiles"`
+}
+
+// Everything needed to resume a game after a restart
+// the setup and costs are not part of it and come from the config as usual
+type Snapshot struct {
+	Version int `json:"version"`
+	Tick    int `json:"tick"`
+	// seed of the world generator, so chunks generated after the restart
+	// still match the saved ones
+	GeneratorSeed int64                     `json:"generatorSeed"`
+	Chunks        []ChunkSnapshot           `json:"chunks"`
+	Players       []entities.PlayerSnapshot `json:"players"`
+	Raids         []entities.RaidSnapshot   `json:"raids"`
+	Units         []entities.UnitSnapshot   `json:"units"`
+	Reports       []server.ReportSnapshot   `json:"reports"`
+}
+
+// Saves the state of the given server
+// should be called between ticks (see Ticker.PauseAndWait) to get a consistent
+// state, its tick is then the next one to run
+func Take(srv *server.Server, generatorSeed int64) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Version:       Version,
+		Tick:          srv.Ticker().GetTickNumber(),
+		GeneratorSeed: generatorSeed,
+	}
+
+	for _, chunk := range srv.Tilemap().GetGeneratedChunks() {
+		tiles := chunk.GetTiles()
+		data := make([]byte, 0, len(tiles)*2)
+		for _, tile := range tiles {
+			data = append(data, byte(tile.Kind), tile.Value)
+		}
+		snapshot.Chunks = append(snapshot.Chunks, ChunkSnapshot{
+			ChunkPos: chunk.GetChunkPos(),
+			Tiles:    data,
+		})
+	}
+
+	// copy to not hold the server's lock while locking each entity
+	all := make([]server.IEntity, 0)
+	srv.ForEachEntity(func(entity server.IEntity) (shouldStop bool) {
+		all = append(all, entity)
+		return
+	})
+
+	for _, entity := range all {
+		switch e := entity.(type) {
+		case *entities.Player:
+			snapshot.Players = append(snapshot.Players, e.Snapshot())
+		case *entities.Raid:
+			snapshot.Raids = append(snapshot.Raids, e.Snapshot())
+		case server.IUnit:
+			unit, err := entities.SnapshotUnit(e)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Units = append(snapshot.Units, unit)
+		default:
+			log.Warn().Type("entity_type", entity).
+				Str("entity_id", string(entity.GetId())).
+				Msg("SNAPSHOT: Unknown entity type, skipped")
+		}
+	}
+
+	reports, err := srv.SnapshotReports()
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Reports = reports
+
+	return snapshot, nil
+}
+
+// Restores the saved state into the given server, which should be new and
+// not started yet
+func (snapshot *Snapshot) Restore(srv *server.Server) error {
+	if snapshot.Version != Version {
+		return fmt.Errorf(
+			"unsupported snapshot version %d (expected %d)",
+			snapshot.Version, Version,
+		)
+	}
+
+	srv.Ticker().SetTickNumber(snapshot.Tick)
+
+	for _, chunk := range snapshot.Chunks {
+		if len(chunk.Tiles) != terrain.ChunkTileCount*2 {
+			return fmt.Errorf("chunk %v has an invalid size", chunk.ChunkPos)
+		}
+		tiles := make([]terrain.Tile, terrain.ChunkTileCount)
+		for i := range tiles {
+			tiles[i] = terrain.Tile{
+				Kind:  terrain.TileKind(chunk.Tiles[i*2]),
+				Value: chunk.Tiles[i*2+1],
+			}
+		}
+		err := srv.Tilemap().RestoreChunk(chunk.ChunkPos, tiles)
+		if err != nil {
+			return fmt.Errorf("chunk %v: %w", chunk.ChunkPos, err)
+		}
+	}
+
+	// owners first so entities are added back to them when registered
+	for _, player := range snapshot.Players {
+		entities.RestorePlayer(srv, player)
+	}
+	for _, raid := range snapshot.Raids {
+		entities.RestoreRaid(srv, raid)
+	}
+	for _, unit := range snapshot.Units {
+		_, err := entities.RestoreUnit(srv, unit)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := srv.RestoreReports(snapshot.Reports)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("tick", snapshot.Tick).
+		Int("chunks", len(snapshot.Chunks)).
+		Int("players", len(snapshot.Players)).
+		Int("units", len(snapshot.Units)).
+		Msg("Snapshot restored")
+
+	return nil
+}
+
+// Writes the snapshot as gzipped json, the file is only replaced once
+// everything is written so a crash while saving keeps the previous one
+func (snapshot *Snapshot) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := gzip.NewWriter(tmp)
+	err = json.NewEncoder(writer).Encode(snapshot)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func ReadFile(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	snapshot := new(Snapshot)
+	err = json.NewDecoder(reader).Decode(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
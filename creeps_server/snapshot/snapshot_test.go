@@ -0,0 +1,222 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+const testSeed = 42
+
+// sorts everything that is saved in no particular order so two snapshots of
+// the same state are equal
+func normalize(snapshot *Snapshot) {
+	slices.SortFunc(snapshot.Chunks, func(a, b ChunkSnapshot) int {
+		return ComparePoints(a.ChunkPos, b.ChunkPos)
+	})
+	slices.SortFunc(snapshot.Players, func(a, b entities.PlayerSnapshot) int {
+		return strings.Compare(string(a.Id), string(b.Id))
+	})
+	for _, player := range snapshot.Players {
+		slices.SortFunc(player.Buildings, func(a, b entities.BuildingSnapshot) int {
+			return ComparePoints(a.Position, b.Position)
+		})
+	}
+	slices.SortFunc(snapshot.Raids, func(a, b entities.RaidSnapshot) int {
+		return strings.Compare(string(a.Id), string(b.Id))
+	})
+	slices.SortFunc(snapshot.Units, func(a, b entities.UnitSnapshot) int {
+		return strings.Compare(string(a.Id), string(b.Id))
+	})
+	slices.SortFunc(snapshot.Reports, func(a, b server.ReportSnapshot) int {
+		return strings.Compare(string(a.Report), string(b.Report))
+	})
+}
+
+func mustMarshal(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		// fills the server that is saved
+		setup func(srv *server.Server)
+	}{
+		{
+			name:  "empty",
+			setup: func(srv *server.Server) {},
+		},
+		{
+			name: "one player",
+			setup: func(srv *server.Server) {
+				servertest.SpawnPlayer(srv, "alice", Point{X: 0, Y: 0})
+			},
+		},
+		{
+			name: "players, raid and reports",
+			setup: func(srv *server.Server) {
+				alice, _, _ := servertest.SpawnPlayer(srv, "alice", Point{X: 0, Y: 0})
+				servertest.SpawnPlayer(srv, "bob", Point{X: 40, Y: 0})
+				entities.NewRaid(srv, alice.GetId()).Register()
+
+				srv.AddReport(&model.FarmReport{
+					Report: model.Report{
+						OpCode:   model.OpCodeFarm,
+						ReportId: uid.GenUid(),
+						Login:    "alice",
+						Status:   "SUCCESS",
+					},
+					FoodQuantity: 3,
+				})
+				srv.AddReport(&model.ErrorReport{
+					Report: model.Report{
+						OpCode:   model.OpCodeFarm,
+						ReportId: uid.GenUid(),
+						Login:    "bob",
+						Status:   "ERROR",
+					},
+					ErrorCode: "noresource",
+					Error:     "There is nothing to farm here",
+				})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := servertest.NewServer(testSeed)
+			original.Ticker().SetTickNumber(42)
+			test.setup(original)
+
+			saved, err := Take(original, testSeed)
+			if err != nil {
+				t.Fatalf("take: %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "snapshot.json.gz")
+			err = saved.WriteFile(path)
+			if err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			read, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+
+			restored := servertest.NewServer(testSeed)
+			err = read.Restore(restored)
+			if err != nil {
+				t.Fatalf("restore: %v", err)
+			}
+			if tick := restored.Ticker().GetTickNumber(); tick != 42 {
+				t.Errorf("expected tick 42, got %d", tick)
+			}
+
+			resaved, err := Take(restored, testSeed)
+			if err != nil {
+				t.Fatalf("take after restore: %v", err)
+			}
+
+			normalize(saved)
+			normalize(resaved)
+			expected, got := mustMarshal(saved), mustMarshal(resaved)
+			if string(expected) != string(got) {
+				t.Errorf("snapshot changed after a round trip\nexpected: %s\ngot:      %s", expected, got)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsOtherVersions(t *testing.T) {
+	tests := []struct {
+		version int
+		valid   bool
+	}{
+		{version: Version, valid: true},
+		{version: Version + 1, valid: false},
+		{version: 0, valid: false},
+	}
+
+	for _, test := range tests {
+		snapshot := &Snapshot{Version: test.version}
+		err := snapshot.Restore(servertest.NewServer(testSeed))
+		if test.valid && err != nil {
+			t.Errorf("version %d: unexpected error %v", test.version, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("version %d: expected an error", test.version)
+		}
+	}
+}
+
+// an action in progress when saved finishes after the restore and its unit
+// keeps acting
+func TestRestoredActionFinishes(t *testing.T) {
+	original := servertest.NewServer(testSeed)
+	alice, _, _ := servertest.SpawnPlayer(original, "alice", Point{X: 0, Y: 0})
+	raid := entities.NewRaid(original, alice.GetId())
+	raid.Register()
+
+	for x := 1; x <= 6; x++ {
+		original.Tilemap().SetTile(Point{X: x, Y: 0}, terrain.Tile{Kind: terrain.TileGrass})
+	}
+	raider := entities.NewRaiderUnit(original, raid.GetId(), Point{X: 0, Y: 0}, 1)
+	raider.SetPosition(Point{X: 6, Y: 0})
+	raider.Register()
+	raider.Tick()
+
+	action := raider.GetLastAction()
+	if action == nil || action.OpCode != model.OpCodeMoveLeft {
+		t.Fatalf("expected the raider to move left, got %+v", action)
+	}
+
+	saved, err := Take(original, testSeed)
+	if err != nil {
+		t.Fatalf("take: %v", err)
+	}
+	restored := servertest.NewServer(testSeed)
+	err = saved.Restore(restored)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restoredRaider := restored.GetEntity(raider.GetId()).(*entities.RaiderUnit)
+
+	ticker := restored.Ticker()
+	ticker.Pause()
+	go ticker.Start()
+	defer ticker.Stop()
+	ticker.Step(action.FinishedAtTick - saved.Tick + 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ticker.GetTickNumber() <= action.FinishedAtTick {
+		if time.Now().After(deadline) {
+			t.Fatalf("the ticker did not reach the tick %d", action.FinishedAtTick)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ticker.PauseAndWait()
+
+	if position := restoredRaider.GetPosition(); position != (Point{X: 5, Y: 0}) {
+		t.Errorf("expected the restored move to be applied, the raider is at %v", position)
+	}
+	next := restoredRaider.GetLastAction()
+	if next == nil || next.StartedAtTick != action.FinishedAtTick {
+		t.Errorf("expected the raider to move again at the tick %d, got %+v", action.FinishedAtTick, next)
+	}
+}
@@ -2,17 +2,28 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
 	"github.com/heavenston/creeps_server/creeps_lib/model"
 	. "github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_server/achievements"
+	"github.com/heavenston/creeps_server/creeps_server/admin_api"
+	"github.com/heavenston/creeps_server/creeps_server/config"
 	"github.com/heavenston/creeps_server/creeps_server/epita_api"
 	"github.com/heavenston/creeps_server/creeps_server/generator"
+	"github.com/heavenston/creeps_server/creeps_server/manager"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
 	. "github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/snapshot"
 	"github.com/heavenston/creeps_server/creeps_server/viewer"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 func startServ(*kong.Context) {
@@ -28,42 +39,131 @@ func startServ(*kong.Context) {
 		zerolog.SetGlobalLevel(zerolog.WarnLevel)
 	}
 
-	generator := generator.NewNoiseGenerator(time.Now().UnixMilli())
-	tilemap := NewTilemap(generator)
+	if CLI.Replay != "" {
+		startReplay()
+		return
+	}
 
-	setup := defaultSetup
-	costs := defaultCosts
+	conf, confPath, err := config.Load(CLI.Config)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", CLI.Config).Msg("Invalid config")
+	}
+	if confPath != "" {
+		log.Info().Str("path", confPath).Msg("Config loaded")
+	}
 
-	if CLI.Tps > 0 {
-		setup.TicksPerSecond = CLI.Tps
+	overrideSetup(&conf.Setup)
+
+	if CLI.Master {
+		startMaster(conf)
+		return
 	}
-	if CLI.Hector != nil {
-		setup.EnableGC = *CLI.Hector
+
+	setup := conf.Setup
+	costs := conf.Costs
+
+	var saved *snapshot.Snapshot
+	seed := time.Now().UnixMilli()
+	if CLI.Seed != nil {
+		seed = *CLI.Seed
 	}
-	if CLI.Enemies != nil {
-		setup.EnableEnemies = *CLI.Enemies
+	// seeds everything else, the map keeps the seed it was generated with
+	simulationSeed := seed
+	if CLI.Resume != "" {
+		var err error
+		saved, err = snapshot.ReadFile(CLI.Resume)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", CLI.Resume).Msg("Could not read snapshot")
+		}
+		seed = saved.GeneratorSeed
+		// so the uids of the saved game are not generated again
+		simulationSeed += int64(saved.Tick)
 	}
 
+	generator := generator.NewNoiseGenerator(seed)
+	if setup.BoundedWorld {
+		generator.SetBounds(setup.WorldBounds())
+	}
+	tilemap := NewTilemap(generator)
+
 	srv := NewServer(&tilemap, &setup, &costs)
-	srv.SetDefaultPlayerResources(model.Resources{
-		Rock:      30,
-		Wood:      30,
-		Food:      30,
-		Oil:       0,
-		Copper:    0,
-		WoodPlank: 0,
-	})
+	if CLI.Seed != nil {
+		srv.SeedUids(simulationSeed)
+		srv.SeedSpawns(simulationSeed)
+		log.Info().Int64("seed", *CLI.Seed).Msg("Deterministic mode")
+	}
+	if saved != nil {
+		err := saved.Restore(srv)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", CLI.Resume).Msg("Could not restore snapshot")
+		}
+	}
+	var saver *snapshot.AutoSaver
+	if CLI.Save != "" {
+		saver = snapshot.StartAutoSave(srv, CLI.Save, seed, CLI.SaveInterval)
+	}
+	var recorder *viewer.Recorder
+	if CLI.Record != "" {
+		var err error
+		recorder, err = viewer.StartRecording(srv, CLI.Record)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", CLI.Record).Msg("Could not open replay file")
+		}
+	}
+	if saver != nil || recorder != nil {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go (func() {
+			<-signals
+			log.Info().Msg("Shutting down")
+			code := 0
+			if saver != nil && saver.Save() != nil {
+				code = 1
+			}
+			if recorder != nil && recorder.Close() != nil {
+				code = 1
+			}
+			os.Exit(code)
+		})()
+	}
+	if CLI.Paused {
+		srv.Ticker().Pause()
+	}
+	if setup.TrackAchievements {
+		achievements.Track(srv)
+	}
+	var gameMatch *match.Match
+	if setup.Match.Enabled {
+		gameMatch = match.Track(srv, CLI.Results)
+	}
+	srv.SetDefaultPlayerResources(conf.PlayerResources)
+	srv.RegisterMetrics(metrics.Default)
 
 	api_server := &epita_api.ApiServer{
-		Addr:   fmt.Sprintf("%s:%d", CLI.ApiHost, CLI.ApiPort),
-		Server: srv,
+		Addr:          fmt.Sprintf("%s:%d", CLI.ApiHost, CLI.ApiPort),
+		Server:        srv,
+		AllowAddrAuth: CLI.ApiAddrAuth,
+		Match:         gameMatch,
 	}
 	go api_server.Start()
 
+	if CLI.AdminSecret != "" {
+		admin_server := &admin_api.AdminServer{
+			Addr:   fmt.Sprintf("%s:%d", CLI.AdminHost, CLI.AdminPort),
+			Server: srv,
+			Secret: CLI.AdminSecret,
+			Match:  gameMatch,
+		}
+		go admin_server.Start()
+	}
+
 	viewer_server := &viewer.ViewerServer{
-		Addr:   fmt.Sprintf("%s:%d", CLI.ViewerHost, CLI.ViewerPort),
-		Server: srv,
+		Addr:               fmt.Sprintf("%s:%d", CLI.ViewerHost, CLI.ViewerPort),
+		Server:             srv,
+		AllowTickerControl: CLI.ViewerTickerControl,
+		Match:              gameMatch,
 	}
+	viewer_server.RegisterMetrics(metrics.Default)
 	go viewer_server.Start()
 
 	tilemap.GenerateChunk(Point{X: 0, Y: 0})
@@ -71,5 +171,85 @@ func startServ(*kong.Context) {
 	tilemap.GenerateChunk(Point{X: -1, Y: 0})
 	tilemap.GenerateChunk(Point{X: -1, Y: -1})
 
+	if CLI.RegisterWith != "" {
+		registration := &manager.Registration{
+			MasterUrl: CLI.RegisterWith,
+			Secret:    CLI.MasterSecret,
+			Id:        CLI.GameId,
+			Name:      setup.ServerId,
+			ApiUrl:    CLI.PublicApiUrl,
+			ViewerUrl: CLI.PublicViewerUrl,
+			Server:    srv,
+			Match:     gameMatch,
+		}
+		if registration.Id == "" {
+			registration.Id = setup.ServerId
+		}
+		if registration.ApiUrl == "" {
+			registration.ApiUrl = fmt.Sprintf("http://%s:%d", CLI.ApiHost, CLI.ApiPort)
+		}
+		if registration.ViewerUrl == "" {
+			registration.ViewerUrl = fmt.Sprintf("ws://%s:%d/websocket", CLI.ViewerHost, CLI.ViewerPort)
+		}
+		go registration.Start()
+	}
+
 	srv.Start()
 }
+
+// applies the command line overrides to the setup of the config
+func overrideSetup(setup *model.SetupResponse) {
+	if CLI.WorldSize > 0 {
+		setup.BoundedWorld = true
+		setup.WorldDimension = Point{X: CLI.WorldSize, Y: CLI.WorldSize}
+	}
+	if CLI.Tps > 0 {
+		setup.TicksPerSecond = CLI.Tps
+	} else if CLI.Tps < 0 {
+		setup.TicksPerSecond = 0
+	}
+	if CLI.Hector != nil {
+		setup.EnableGC = *CLI.Hector
+	}
+	if CLI.Enemies != nil {
+		setup.EnableEnemies = *CLI.Enemies
+	}
+	if CLI.Achievements != nil {
+		setup.TrackAchievements = *CLI.Achievements
+	}
+	if CLI.Match != nil {
+		setup.Match.Enabled = *CLI.Match
+	}
+}
+
+// hosts games created from the lobby api, see manager.Manager
+func startMaster(conf *config.Config) {
+	if CLI.Resume != "" || CLI.Save != "" || CLI.Record != "" || CLI.Seed != nil || CLI.Results != "" {
+		log.Fatal().Msg("--resume, --save, --record, --seed and --results cannot be used with --master")
+	}
+
+	master := manager.NewManager(*conf)
+	master.Addr = fmt.Sprintf("%s:%d", CLI.MasterHost, CLI.MasterPort)
+	master.Secret = CLI.MasterSecret
+	master.AllowAddrAuth = CLI.ApiAddrAuth
+	master.AllowTickerControl = CLI.ViewerTickerControl
+	master.AdminSecret = CLI.AdminSecret
+	master.RegisterMetrics(metrics.Default)
+
+	if master.Secret == "" {
+		log.Warn().Msg("The master has no secret, anyone can create and delete games")
+	}
+
+	master.Start()
+}
+
+func startReplay() {
+	replay_server := &viewer.ReplayServer{
+		Addr: fmt.Sprintf("%s:%d", CLI.ViewerHost, CLI.ViewerPort),
+	}
+	err := replay_server.Load(CLI.Replay)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", CLI.Replay).Msg("Could not read replay")
+	}
+	replay_server.Start()
+}
@@ -5,7 +5,10 @@ import (
 
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
 	"github.com/heavenston/creeps_server/creeps_lib/model"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
 	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
 )
 
 type message struct {
@@ -31,6 +34,22 @@ type fullChunkContent struct {
 	Tiles []byte `json:"tiles"`
 }
 
+func newFullChunkContent(chunk *terrain.Chunk) fullChunkContent {
+	tiles := make([]byte, 2*terrain.ChunkSize*terrain.ChunkSize)
+	for y := 0; y < terrain.ChunkSize; y++ {
+		for x := 0; x < terrain.ChunkSize; x++ {
+			i := 2 * (x + y*terrain.ChunkSize)
+			tile := chunk.GetTile(Point{X: x, Y: y})
+			tiles[i] = byte(tile.Kind)
+			tiles[i+1] = byte(tile.Value)
+		}
+	}
+	return fullChunkContent{
+		ChunkPos: chunk.GetChunkPos(),
+		Tiles:    tiles,
+	}
+}
+
 type tileChangeContent struct {
 	TilePos Point `json:"tilePos"`
 	Kind    byte  `json:"kind"`
@@ -41,7 +60,22 @@ type tileChangeContent struct {
 type actionData struct {
 	ActionOpCode model.ActionOpCode `json:"actionOpCode"`
 	ReportId     uid.Uid            `json:"reportId"`
-	Parameter    any                `json:"parameter,omitempty"`
+	// effective cast time in ticks
+	Cast           int `json:"cast"`
+	StartedAtTick  int `json:"startedAtTick"`
+	FinishedAtTick int `json:"finishedAtTick"`
+	Parameter      any `json:"parameter,omitempty"`
+}
+
+func newActionData(action *server.Action) actionData {
+	return actionData{
+		ActionOpCode:   action.OpCode,
+		ReportId:       action.ReportId,
+		Cast:           action.Cast,
+		StartedAtTick:  action.StartedAtTick,
+		FinishedAtTick: action.FinishedAtTick,
+		Parameter:      action.Parameter,
+	}
 }
 
 // sent by the server when a unit spawned
@@ -53,11 +87,25 @@ type unitContent struct {
 	Upgraded bool    `json:"upgraded"`
 }
 
+func newUnitContent(unit server.IUnit) unitContent {
+	return unitContent{
+		OpCode:   unit.GetOpCode(),
+		UnitId:   unit.GetId(),
+		Owner:    unit.GetOwner(),
+		Position: unit.GetPosition(),
+		Upgraded: unit.IsUpgraded(),
+	}
+}
+
 // sent by the server when a unit dies or gets out of the subscribed chunks
 type unitDespawnContent struct {
 	UnitId uid.Uid `json:"unitId"`
 }
 
+type unitUpgradedContent struct {
+	UnitId uid.Uid `json:"unitId"`
+}
+
 type unitStartedActionContent struct {
 	UnitId uid.Uid    `json:"unitId"`
 	Action actionData `json:"action"`
@@ -76,10 +124,68 @@ type playerSpawnContent struct {
 	Resources     model.Resources `json:"resources"`
 }
 
+func newPlayerSpawnContent(player *entities.Player) playerSpawnContent {
+	return playerSpawnContent{
+		Id:            player.GetId(),
+		SpawnPosition: player.GetSpawnPoint(),
+		Username:      player.GetUsername(),
+		Resources:     player.GetResources(),
+	}
+}
+
 type playerDespawnContent struct {
 	Id uid.Uid `json:"id"`
 }
 
+// sent by the server when the resources of a known player changed, at most
+// once per tick for each player
+type playerResourcesContent struct {
+	Id        uid.Uid         `json:"id"`
+	Resources model.Resources `json:"resources"`
+}
+
+// sent by the server when a player sends a message to another
+type playerMessageContent struct {
+	Sender    uid.Uid `json:"sender"`
+	Recipient uid.Uid `json:"recipient"`
+	Message   string  `json:"message"`
+}
+
+// sent by the server when a player is awarded an achievement
+type playerAchievementContent struct {
+	Id          uid.Uid `json:"id"`
+	Achievement string  `json:"achievement"`
+}
+
+// sent by the server when a raid is started against a player
+type raidStartedContent struct {
+	Id             uid.Uid `json:"id"`
+	Owner          uid.Uid `json:"owner"`
+	CampPosition   Point   `json:"campPosition"`
+	TargetPosition Point   `json:"targetPosition"`
+}
+
+// sent by the server when a player sends a command to the api
+type commandContent struct {
+	Response  model.CommandResponse `json:"response"`
+	Parameter any                   `json:"parameter,omitempty"`
+}
+
+// only found in replay files, used to keep track of the units' positions
+// when seeking
+type unitMovedContent struct {
+	UnitId uid.Uid `json:"unitId"`
+	From   Point   `json:"from"`
+	To     Point   `json:"to"`
+}
+
+// sent by the server when the ticker is paused, resumed or its speed changes
+type tickerStateContent struct {
+	// 0 means as fast as possible
+	TicksPerSecond float64 `json:"ticksPerSecond"`
+	Paused         bool    `json:"paused"`
+}
+
 // sent by the front end to subscribe to a chunk content
 type subscribeRequestContent struct {
 	ChunkPos Point `json:"chunkPos"`
@@ -89,3 +195,35 @@ type subscribeRequestContent struct {
 type unsubscribeRequestContent struct {
 	ChunkPos Point `json:"chunkPos"`
 }
+
+// sent by the front end to run the given amount of ticks while paused
+// (only if ticker control is allowed)
+type stepRequestContent struct {
+	Ticks int `json:"ticks"`
+}
+
+// sent by the front end to change the speed of the server, 0 for as fast as
+// possible (only if ticker control is allowed)
+type setTpsRequestContent struct {
+	TicksPerSecond float64 `json:"ticksPerSecond"`
+}
+
+// sent by the server when the playback changes and regularly while playing
+type replayStateContent struct {
+	Tick      int     `json:"tick"`
+	FirstTick int     `json:"firstTick"`
+	LastTick  int     `json:"lastTick"`
+	Paused    bool    `json:"paused"`
+	Speed     float64 `json:"speed"`
+}
+
+// sent by the front end to go to the given tick of the replay
+type replaySeekRequestContent struct {
+	Tick int `json:"tick"`
+}
+
+// sent by the front end to change the playback speed, 1 is the recorded
+// speed
+type replaySpeedRequestContent struct {
+	Speed float64 `json:"speed"`
+}
@@ -0,0 +1,18 @@
+package viewer
+
+import "github.com/heavenston/creeps_server/creeps_server/metrics"
+
+// Adds to the registry the metrics of the viewer's connections
+// must be called only once per registry
+func (viewer *ViewerServer) RegisterMetrics(registry *metrics.Registry) {
+	registry.AddGauge(
+		"creeps_viewer_connections",
+		"Open websocket connections of the viewer",
+		&viewer.websocketConnections,
+	)
+	registry.AddGauge(
+		"creeps_viewer_chunk_subscriptions",
+		"Chunks subscribed to by viewer connections",
+		&viewer.chunkSubscriptions,
+	)
+}
@@ -0,0 +1,286 @@
+package viewer
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_server/epita_api"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/server"
+	"github.com/heavenston/creeps_server/creeps_server/server/entities"
+	"github.com/rs/zerolog/log"
+)
+
+// a line of a replay file, the content is the same as the viewer message of
+// the same kind
+type replayEntry struct {
+	Tick    int             `json:"tick"`
+	Kind    string          `json:"kind"`
+	Content json.RawMessage `json:"content"`
+}
+
+// Writes everything happening on a server to a replay file, see
+// StartRecording and ReplayServer
+type Recorder struct {
+	server *server.Server
+	path   string
+
+	file   *os.File
+	writer *bufio.Writer
+
+	// closed by Close to stop the recording goroutine
+	stop chan struct{}
+	// closed by the recording goroutine once everything is written
+	stopped chan struct{}
+}
+
+// Appends the state of the server and then all its events to the given
+// file, the file is created if it doesn't exist
+// should be called before the ticker is started so the initial state is
+// consistent
+func StartRecording(srv *server.Server, path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &Recorder{
+		server:  srv,
+		path:    path,
+		file:    file,
+		writer:  bufio.NewWriter(file),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	// subscribe first to not miss anything happening while the state is
+	// written, the replay ignores duplicates
+	serverEventsChannel := make(chan server.IServerEvent, 4096)
+	serverEventsHandle := srv.Events().Subscribe(serverEventsChannel, AABB{})
+	tilemapEventsChannel := make(chan terrain.TilemapChunkEvent, 4096)
+	tilemapEventsHandle := srv.Tilemap().UpdatedEventProvider.Subscribe(tilemapEventsChannel)
+
+	recorder.writeState()
+
+	go (func() {
+		defer close(recorder.stopped)
+		defer serverEventsHandle.Cancel()
+		defer tilemapEventsHandle.Cancel()
+
+		// logged when it changes
+		var dropped uint64
+		checkDropped := func() {
+			total := serverEventsHandle.GetDroppedCount() + tilemapEventsHandle.GetDroppedCount()
+			if total != dropped {
+				log.Warn().
+					Str("path", path).
+					Uint64("dropped", total-dropped).
+					Uint64("total_dropped", total).
+					Msg("Replay is missing events (the recorder is too slow)")
+				dropped = total
+			}
+		}
+
+		for {
+			select {
+			case <-recorder.stop:
+				// events emitted before the stop are still waiting
+				for len(serverEventsChannel) > 0 || len(tilemapEventsChannel) > 0 {
+					select {
+					case event := <-serverEventsChannel:
+						recorder.recordServerEvent(event)
+					case event := <-tilemapEventsChannel:
+						recorder.recordTilemapEvent(event)
+					}
+				}
+				recorder.flush()
+				checkDropped()
+				return
+			case event := <-serverEventsChannel:
+				recorder.recordServerEvent(event)
+			case event := <-tilemapEventsChannel:
+				recorder.recordTilemapEvent(event)
+			}
+
+			if len(serverEventsChannel) == 0 && len(tilemapEventsChannel) == 0 {
+				recorder.flush()
+				checkDropped()
+			}
+		}
+	})()
+
+	log.Info().Str("path", path).Msg("Recording replay")
+
+	return recorder, nil
+}
+
+// Stops the recording and closes the file once everything is written
+func (recorder *Recorder) Close() error {
+	close(recorder.stop)
+	<-recorder.stopped
+	return recorder.file.Close()
+}
+
+// writes an entry of the given tick, which should be the one of the event
+// and not the current one as events can wait in the channels
+func (recorder *Recorder) write(tick int, kind string, content any) {
+	contentbytes, err := json.Marshal(content)
+	if err != nil {
+		log.Warn().Err(err).Str("kind", kind).Msg("replay entry ser error")
+		return
+	}
+	bytes, err := json.Marshal(replayEntry{
+		Tick:    tick,
+		Kind:    kind,
+		Content: contentbytes,
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("kind", kind).Msg("replay entry ser error")
+		return
+	}
+
+	recorder.writer.Write(bytes)
+	recorder.writer.WriteByte('\n')
+}
+
+func (recorder *Recorder) flush() {
+	err := recorder.writer.Flush()
+	if err != nil {
+		log.Error().Err(err).Str("path", recorder.path).Msg("Could not write replay")
+	}
+}
+
+// writes what a new viewer would receive, a replay can start from there
+func (recorder *Recorder) writeState() {
+	srv := recorder.server
+	tick := srv.Ticker().GetTickNumber()
+
+	recorder.write(tick, "init", initContent{
+		ChunkSize: terrain.ChunkSize,
+		Setup:     srv.GetCurrentSetup(),
+		Costs:     srv.GetCosts(),
+	})
+	state := srv.Ticker().GetState()
+	recorder.write(tick, "tickerState", tickerStateContent{
+		TicksPerSecond: state.TicksPerSecond,
+		Paused:         state.Paused,
+	})
+
+	for _, chunk := range srv.Tilemap().GetGeneratedChunks() {
+		recorder.write(tick, "fullchunk", newFullChunkContent(chunk))
+	}
+
+	// players first so units always have a known owner
+	all := make([]server.IEntity, 0)
+	srv.ForEachEntity(func(entity server.IEntity) (shouldStop bool) {
+		all = append(all, entity)
+		return
+	})
+	for _, entity := range all {
+		if player, ok := entity.(*entities.Player); ok {
+			recorder.write(tick, "playerSpawn", newPlayerSpawnContent(player))
+		}
+	}
+	for _, entity := range all {
+		if unit, ok := entity.(server.IUnit); ok {
+			recorder.write(tick, "unit", newUnitContent(unit))
+		}
+	}
+
+	recorder.flush()
+}
+
+func (recorder *Recorder) recordServerEvent(event server.IServerEvent) {
+	switch e := event.(type) {
+	case *server.UnitSpawnEvent:
+		recorder.write(event.GetTick(), "unit", newUnitContent(e.Unit))
+	case *server.UnitDespawnEvent:
+		recorder.write(event.GetTick(), "unitDespawned", unitDespawnContent{
+			UnitId: e.Unit.GetId(),
+		})
+	case *server.UnitMovedEvent:
+		recorder.write(event.GetTick(), "unitMoved", unitMovedContent{
+			UnitId: e.Unit.GetId(),
+			From:   e.From,
+			To:     e.To,
+		})
+	case *server.UnitUpgradedEvent:
+		recorder.write(event.GetTick(), "unitUpgraded", unitUpgradedContent{
+			UnitId: e.Unit.GetId(),
+		})
+	case *server.UnitStartedActionEvent:
+		recorder.write(event.GetTick(), "unitStartedAction", unitStartedActionContent{
+			UnitId: e.Unit.GetId(),
+			Action: newActionData(e.Action),
+		})
+	case *server.UnitFinishedActionEvent:
+		recorder.write(event.GetTick(), "unitFinishedAction", unitFinishedActionContent{
+			UnitId: e.Unit.GetId(),
+			Action: newActionData(e.Action),
+			Report: e.Report,
+		})
+	case *server.TickerStateEvent:
+		recorder.write(event.GetTick(), "tickerState", tickerStateContent{
+			TicksPerSecond: e.State.TicksPerSecond,
+			Paused:         e.State.Paused,
+		})
+	case *entities.PlayerSpawnEvent:
+		recorder.write(event.GetTick(), "playerSpawn", newPlayerSpawnContent(e.Player))
+	case *entities.PlayerDespawnEvent:
+		recorder.write(event.GetTick(), "playerDespawn", playerDespawnContent{
+			Id: e.Player.GetId(),
+		})
+	case *entities.PlayerMessageEvent:
+		recorder.write(event.GetTick(), "playerMessage", playerMessageContent{
+			Sender:    e.Sender.GetId(),
+			Recipient: e.Recipient.GetId(),
+			Message:   e.Message,
+		})
+	case *entities.PlayerAchievementEvent:
+		recorder.write(event.GetTick(), "playerAchievement", playerAchievementContent{
+			Id:          e.Player.GetId(),
+			Achievement: e.Achievement,
+		})
+	case *entities.PlayerResourcesEvent:
+		recorder.write(event.GetTick(), "playerResources", playerResourcesContent{
+			Id:        e.Player.GetId(),
+			Resources: e.Resources,
+		})
+	case *entities.RaidStartedEvent:
+		recorder.write(event.GetTick(), "raidStarted", raidStartedContent{
+			Id:             e.Raid.GetId(),
+			Owner:          e.Raid.GetOwner(),
+			CampPosition:   e.Raid.GetCampPosition(),
+			TargetPosition: e.Raid.GetTargetPosition(),
+		})
+	case *match.StateEvent:
+		recorder.write(event.GetTick(), "matchState", e.Status)
+	case *epita_api.CommandEvent:
+		recorder.write(event.GetTick(), "command", commandContent{
+			Response:  e.Response,
+			Parameter: e.Parameter,
+		})
+	default:
+		log.Debug().Type("event_type", event).Msg("REPLAY: Unknown event type, skipped")
+	}
+}
+
+func (recorder *Recorder) recordTilemapEvent(event terrain.TilemapChunkEvent) {
+	switch e := event.Event.(type) {
+	case terrain.TileUpdateChunkEvent:
+		recorder.write(event.Tick, "tileChange", tileChangeContent{
+			TilePos: e.UpdatedPosition.Add(event.ChunkPos.Times(terrain.ChunkSize)),
+			Kind:    byte(e.NewValue.Kind),
+			Value:   e.NewValue.Value,
+		})
+	case terrain.GeneratedChunkEvent:
+		chunk := recorder.server.Tilemap().GetChunk(event.ChunkPos)
+		if chunk == nil {
+			return
+		}
+		recorder.write(event.Tick, "fullchunk", newFullChunkContent(chunk))
+	}
+}
@@ -0,0 +1,77 @@
+package viewer
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_server/servertest"
+)
+
+// every event emitted before Close must be in the replay
+func TestRecorderKeepsLastEvents(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiles int
+	}{
+		{name: "one tile", tiles: 1},
+		{name: "many tiles", tiles: 500},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := servertest.NewServer(0)
+			path := filepath.Join(t.TempDir(), "replay.jsonl")
+			recorder, err := StartRecording(srv, path)
+			if err != nil {
+				t.Fatalf("start: %v", err)
+			}
+
+			changed := make(map[Point]bool)
+			for i := 0; i < test.tiles; i++ {
+				p := Point{X: i % 50, Y: i / 50}
+				srv.Tilemap().SetTile(p, terrain.Tile{Kind: terrain.TileRoad})
+				changed[p] = true
+			}
+
+			err = recorder.Close()
+			if err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			scanner := bufio.NewScanner(file)
+			scanner.Buffer(nil, 1<<20)
+			for scanner.Scan() {
+				var entry replayEntry
+				err := json.Unmarshal(scanner.Bytes(), &entry)
+				if err != nil {
+					t.Fatalf("invalid entry %s: %v", scanner.Text(), err)
+				}
+				if entry.Kind != "tileChange" {
+					continue
+				}
+				var content tileChangeContent
+				err = json.Unmarshal(entry.Content, &content)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if content.Kind == byte(terrain.TileRoad) {
+					delete(changed, content.TilePos)
+				}
+			}
+			if len(changed) > 0 {
+				t.Errorf("%d of the %d tile changes are missing", len(changed), test.tiles)
+			}
+		})
+	}
+}
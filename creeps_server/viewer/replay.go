@@ -0,0 +1,539 @@
+package viewer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
+	"github.com/heavenston/creeps_server/creeps_lib/terrain"
+	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	replayFrameDuration = time.Millisecond * 50
+	// used when the recorded server was running as fast as possible
+	replayUnlimitedTps = 50.
+)
+
+// Plays a replay file (see Recorder) through the viewer's websocket protocol
+// every client gets its own playback, controlled with the replay* messages
+type ReplayServer struct {
+	Addr string
+
+	// see Load
+	entries []replayEntry
+}
+
+// state of the recorded world at a given point of the replay, used to send
+// everything at once when seeking
+type replayWorld struct {
+	init        json.RawMessage
+	tickerState tickerStateContent
+	chunks      map[Point]*fullChunkContent
+	players     map[uid.Uid]playerSpawnContent
+	units       map[uid.Uid]unitContent
+}
+
+func newReplayWorld() *replayWorld {
+	return &replayWorld{
+		chunks:  make(map[Point]*fullChunkContent),
+		players: make(map[uid.Uid]playerSpawnContent),
+		units:   make(map[uid.Uid]unitContent),
+	}
+}
+
+func (world *replayWorld) apply(entry replayEntry) error {
+	switch entry.Kind {
+	case "init":
+		*world = *newReplayWorld()
+		world.init = entry.Content
+	case "tickerState":
+		return json.Unmarshal(entry.Content, &world.tickerState)
+	case "fullchunk":
+		content := new(fullChunkContent)
+		err := json.Unmarshal(entry.Content, content)
+		if err != nil {
+			return err
+		}
+		world.chunks[content.ChunkPos] = content
+	case "tileChange":
+		var content tileChangeContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		chunk := world.chunks[terrain.Global2ContainingChunkCoords(content.TilePos)]
+		if chunk == nil || len(chunk.Tiles) == 0 {
+			return nil
+		}
+		subcoord := terrain.Global2ChunkSubCoords(content.TilePos)
+		i := 2 * (subcoord.X + subcoord.Y*terrain.ChunkSize)
+		chunk.Tiles[i] = content.Kind
+		chunk.Tiles[i+1] = content.Value
+	case "playerSpawn":
+		var content playerSpawnContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		world.players[content.Id] = content
+	case "playerDespawn":
+		var content playerDespawnContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		delete(world.players, content.Id)
+	case "playerResources":
+		var content playerResourcesContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		if player, ok := world.players[content.Id]; ok {
+			player.Resources = content.Resources
+			world.players[content.Id] = player
+		}
+	case "unit":
+		var content unitContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		world.units[content.UnitId] = content
+	case "unitDespawned":
+		var content unitDespawnContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		delete(world.units, content.UnitId)
+	case "unitMoved":
+		var content unitMovedContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		if unit, ok := world.units[content.UnitId]; ok {
+			unit.Position = content.To
+			world.units[content.UnitId] = unit
+		}
+	case "unitUpgraded":
+		var content unitUpgradedContent
+		err := json.Unmarshal(entry.Content, &content)
+		if err != nil {
+			return err
+		}
+		if unit, ok := world.units[content.UnitId]; ok {
+			unit.Upgraded = true
+			world.units[content.UnitId] = unit
+		}
+	}
+	return nil
+}
+
+// the playback of a single client
+type replayPlayback struct {
+	replay *ReplayServer
+	conn   *connection
+
+	// guards everything below, held while sending messages
+	lock sync.Mutex
+	// index of the next entry to play
+	position int
+	tick     float64
+	paused   bool
+	speed    float64
+	world    *replayWorld
+
+	// what the client has been sent
+	sentChunks   map[Point]bool
+	knownUnits   map[uid.Uid]bool
+	knownPlayers map[uid.Uid]bool
+}
+
+// Reads the given replay file, lines that cannot be read (like the last one
+// if the server crashed while writing it) are skipped
+// if the file contains multiple recordings they are played one after the
+// other
+func (replay *ReplayServer) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries := make([]replayEntry, 0)
+	// added to the ticks of a recording so they are after the previous ones
+	offset := 0
+	lastTick := 0
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry replayEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			log.Warn().Err(err).Int("line", line).Msg("Invalid replay entry, skipped")
+			continue
+		}
+
+		if entry.Kind == "init" && entry.Tick+offset < lastTick {
+			offset = lastTick - entry.Tick
+		}
+		entry.Tick += offset
+		lastTick = entry.Tick
+
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(entries) == 0 || entries[0].Kind != "init" {
+		return errors.New("not a replay file")
+	}
+
+	replay.entries = entries
+	log.Info().Str("path", path).
+		Int("entries", len(entries)).
+		Int("lastTick", lastTick).
+		Msg("Replay loaded")
+	return nil
+}
+
+func (replay *ReplayServer) firstTick() int {
+	return replay.entries[0].Tick
+}
+
+func (replay *ReplayServer) lastTick() int {
+	return replay.entries[len(replay.entries)-1].Tick
+}
+
+func (playback *replayPlayback) sendState() {
+	playback.conn.sendMessage("replayState", replayStateContent{
+		Tick:      int(playback.tick),
+		FirstTick: playback.replay.firstTick(),
+		LastTick:  playback.replay.lastTick(),
+		Paused:    playback.paused,
+		Speed:     playback.speed,
+	})
+}
+
+// the client animates with the tps so it gets the playback's one
+func (playback *replayPlayback) sendTickerState() {
+	playback.conn.sendMessage("tickerState", tickerStateContent{
+		TicksPerSecond: playback.tps(),
+		Paused:         playback.paused,
+	})
+}
+
+// ticks per second of the playback
+func (playback *replayPlayback) tps() float64 {
+	tps := playback.world.tickerState.TicksPerSecond
+	if tps <= 0 {
+		tps = replayUnlimitedTps
+	}
+	return tps * playback.speed
+}
+
+// despawns everything the client knows about
+func (playback *replayPlayback) clear() {
+	for id := range playback.knownUnits {
+		playback.conn.sendMessage("unitDespawned", unitDespawnContent{
+			UnitId: id,
+		})
+	}
+	for id := range playback.knownPlayers {
+		playback.conn.sendMessage("playerDespawn", playerDespawnContent{
+			Id: id,
+		})
+	}
+	playback.knownUnits = make(map[uid.Uid]bool)
+	playback.knownPlayers = make(map[uid.Uid]bool)
+}
+
+// sends the whole world to the client
+func (playback *replayPlayback) sync() {
+	playback.clear()
+
+	playback.conn.sendMessage("init", playback.world.init)
+	playback.sendTickerState()
+
+	for pos := range playback.sentChunks {
+		if _, ok := playback.world.chunks[pos]; !ok {
+			// not generated yet at this point
+			playback.conn.sendMessage("fullchunk", fullChunkContent{
+				ChunkPos: pos,
+				Tiles:    []byte{},
+			})
+			delete(playback.sentChunks, pos)
+		}
+	}
+	for pos, chunk := range playback.world.chunks {
+		playback.conn.sendMessage("fullchunk", chunk)
+		playback.sentChunks[pos] = true
+	}
+	for id, player := range playback.world.players {
+		playback.conn.sendMessage("playerSpawn", player)
+		playback.knownPlayers[id] = true
+	}
+	for id, unit := range playback.world.units {
+		playback.conn.sendMessage("unit", unit)
+		playback.knownUnits[id] = true
+	}
+}
+
+// sends an entry as it was recorded, avoiding double sends
+func (playback *replayPlayback) forward(entry replayEntry) {
+	kind := entry.Kind
+	content := entry.Content
+
+	switch kind {
+	case "init":
+		playback.clear()
+	case "tickerState":
+		playback.sendTickerState()
+		return
+	case "unitMoved":
+		// the client moves units from the actions' reports
+		return
+	case "fullchunk":
+		var chunk fullChunkContent
+		if json.Unmarshal(content, &chunk) == nil {
+			playback.sentChunks[chunk.ChunkPos] = true
+		}
+	case "unit":
+		var unit unitContent
+		if json.Unmarshal(content, &unit) != nil || playback.knownUnits[unit.UnitId] {
+			return
+		}
+		playback.knownUnits[unit.UnitId] = true
+	case "unitDespawned":
+		var unit unitDespawnContent
+		if json.Unmarshal(content, &unit) != nil || !playback.knownUnits[unit.UnitId] {
+			return
+		}
+		delete(playback.knownUnits, unit.UnitId)
+	case "playerSpawn":
+		var player playerSpawnContent
+		if json.Unmarshal(content, &player) != nil || playback.knownPlayers[player.Id] {
+			return
+		}
+		playback.knownPlayers[player.Id] = true
+	case "playerDespawn":
+		var player playerDespawnContent
+		if json.Unmarshal(content, &player) != nil || !playback.knownPlayers[player.Id] {
+			return
+		}
+		delete(playback.knownPlayers, player.Id)
+	case "playerResources":
+		var player playerResourcesContent
+		if json.Unmarshal(content, &player) != nil || !playback.knownPlayers[player.Id] {
+			return
+		}
+	}
+
+	playback.conn.sendMessage(kind, content)
+}
+
+// plays every entry up to the given tick, forwarding them if send is true
+func (playback *replayPlayback) playUntil(tick int, send bool) {
+	entries := playback.replay.entries
+	for playback.position < len(entries) && entries[playback.position].Tick <= tick {
+		entry := entries[playback.position]
+		playback.position++
+
+		err := playback.world.apply(entry)
+		if err != nil {
+			log.Warn().Err(err).
+				Int("tick", entry.Tick).
+				Str("kind", entry.Kind).
+				Msg("Invalid replay entry")
+			continue
+		}
+		if send {
+			playback.forward(entry)
+		}
+	}
+}
+
+func (playback *replayPlayback) seek(tick int) {
+	tick = mathutils.Max(playback.replay.firstTick(), mathutils.Min(tick, playback.replay.lastTick()))
+	if float64(tick) < playback.tick {
+		playback.position = 0
+		playback.world = newReplayWorld()
+	}
+	playback.playUntil(tick, false)
+	playback.tick = float64(tick)
+	playback.sync()
+}
+
+// advances the playback by the given real time duration
+func (playback *replayPlayback) advance(elapsed time.Duration) {
+	if playback.paused {
+		return
+	}
+
+	playback.tick += elapsed.Seconds() * playback.tps()
+	playback.playUntil(int(playback.tick), true)
+
+	if playback.position >= len(playback.replay.entries) {
+		playback.tick = float64(playback.replay.lastTick())
+		playback.paused = true
+		playback.sendTickerState()
+		playback.sendState()
+	}
+}
+
+func (playback *replayPlayback) handleMessage(mess message) error {
+	playback.lock.Lock()
+	defer playback.lock.Unlock()
+
+	switch mess.Kind {
+	case "replayPlay":
+		if playback.position >= len(playback.replay.entries) {
+			// restart from the begining once finished
+			playback.seek(playback.replay.firstTick())
+		}
+		playback.paused = false
+	case "replayPause":
+		playback.paused = true
+	case "replaySeek":
+		var content replaySeekRequestContent
+		err := json.Unmarshal(mess.Content, &content)
+		if err != nil {
+			return err
+		}
+		playback.seek(content.Tick)
+	case "replaySpeed":
+		var content replaySpeedRequestContent
+		err := json.Unmarshal(mess.Content, &content)
+		if err != nil {
+			return err
+		}
+		if content.Speed <= 0 {
+			return errors.New("the speed must be positive")
+		}
+		playback.speed = content.Speed
+	case "subscribe", "unsubscribe":
+		// everything is sent anyways
+		return nil
+	default:
+		log.Debug().Any("kind", mess.Kind).Msg("Unknown replay message")
+		return nil
+	}
+
+	playback.sendTickerState()
+	playback.sendState()
+	return nil
+}
+
+func (playback *replayPlayback) run(closed chan struct{}) {
+	frames := time.NewTicker(replayFrameDuration)
+	defer frames.Stop()
+
+	last := time.Now()
+	frame := 0
+	for {
+		select {
+		case <-closed:
+			return
+		case now := <-frames.C:
+			playback.lock.Lock()
+			playback.advance(now.Sub(last))
+			frame++
+			if !playback.paused && frame%10 == 0 {
+				playback.sendState()
+			}
+			playback.lock.Unlock()
+			last = now
+		}
+	}
+}
+
+func (replay *ReplayServer) handleClient(conn *websocket.Conn) {
+	defer conn.Close()
+	defer log.Debug().Any("addr", conn.RemoteAddr()).Msg("Replay connection closed")
+
+	log.Debug().Any("addr", conn.RemoteAddr()).Msg("New replay connection")
+
+	playback := &replayPlayback{
+		replay: replay,
+		conn: &connection{
+			socket: conn,
+		},
+		speed:        1,
+		world:        newReplayWorld(),
+		sentChunks:   make(map[Point]bool),
+		knownUnits:   make(map[uid.Uid]bool),
+		knownPlayers: make(map[uid.Uid]bool),
+	}
+
+	playback.lock.Lock()
+	playback.tick = float64(replay.firstTick())
+	playback.playUntil(replay.firstTick(), true)
+	playback.sendState()
+	playback.lock.Unlock()
+
+	closed := make(chan struct{})
+	defer close(closed)
+	go playback.run(closed)
+
+	for {
+		var mess message
+		err := conn.ReadJSON(&mess)
+		if err != nil {
+			if _, ok := err.(*websocket.CloseError); !ok {
+				log.Debug().Err(err).
+					Any("addr", conn.RemoteAddr()).
+					Msg("Websocket fatal error (closing connection)")
+			}
+			return
+		}
+
+		err = playback.handleMessage(mess)
+		if err != nil {
+			log.Debug().Err(err).
+				Any("addr", conn.RemoteAddr()).
+				Msg("Websocket error")
+		}
+	}
+}
+
+func (replay *ReplayServer) Start() {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     func(r *http.Request) bool { return true },
+	}
+
+	router := chi.NewRouter()
+	router.Use(middleware.RealIP)
+	router.Use(middleware.Timeout(60 * time.Second))
+
+	router.HandleFunc("/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Warn().Err(err).Msg("Upgrade failed")
+			return
+		}
+
+		go replay.handleClient(conn)
+	})
+
+	log.Info().Str("addr", replay.Addr).Msg("Replay server starting")
+	http.ListenAndServe(replay.Addr, router)
+}
@@ -10,8 +10,12 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/gorilla/websocket"
 	. "github.com/heavenston/creeps_server/creeps_lib/geom"
+	mathutils "github.com/heavenston/creeps_server/creeps_lib/math_utils"
+	"github.com/heavenston/creeps_server/creeps_lib/model"
 	"github.com/heavenston/creeps_server/creeps_lib/terrain"
 	"github.com/heavenston/creeps_server/creeps_lib/uid"
+	"github.com/heavenston/creeps_server/creeps_server/match"
+	"github.com/heavenston/creeps_server/creeps_server/metrics"
 	"github.com/heavenston/creeps_server/creeps_server/server"
 	"github.com/heavenston/creeps_server/creeps_server/server/entities"
 	"github.com/rs/zerolog/log"
@@ -20,8 +24,20 @@ import (
 type ViewerServer struct {
 	Server *server.Server
 	Addr   string
+	// if set clients can pause, step and change the speed of the ticker
+	AllowTickerControl bool
+	// nil if the game is not played as a match
+	Match *match.Match
+
+	// see RegisterMetrics
+	websocketConnections metrics.Gauge
+	chunkSubscriptions   metrics.Gauge
 }
 
+// playerResources messages are sent at most once per tick and at most once
+// per this interval (when the ticker runs as fast as possible)
+const minPlayerResourcesInterval = 50 * time.Millisecond
+
 type connection struct {
 	socketLock sync.Mutex
 	socket     *websocket.Conn
@@ -34,6 +50,23 @@ type connection struct {
 
 	playersLock  sync.RWMutex
 	knownPlayers map[uid.Uid]bool
+
+	// closed when the connection is closed
+	closed chan struct{}
+}
+
+func (conn *connection) sendMessage(kind string, content any) {
+	contentbytes, err := json.Marshal(content)
+	if err != nil {
+		log.Warn().Err(err).Str("kind", kind).Msg("message ser error")
+		return
+	}
+	conn.socketLock.Lock()
+	conn.socket.WriteJSON(message{
+		Kind:    kind,
+		Content: contentbytes,
+	})
+	conn.socketLock.Unlock()
 }
 
 func (conn *connection) setIsUnitKnown(id uid.Uid, known bool) {
@@ -56,6 +89,9 @@ func (viewer *ViewerServer) handleClientSubscription(
 	chunkPos Point,
 	conn *connection,
 ) {
+	viewer.chunkSubscriptions.Inc()
+	defer viewer.chunkSubscriptions.Dec()
+
 	chunk := viewer.Server.Tilemap().CreateChunk(chunkPos)
 
 	terrainChangeChannel := make(chan any, 2048)
@@ -74,19 +110,7 @@ func (viewer *ViewerServer) handleClientSubscription(
 	serverEventsHandle := viewer.Server.Events().Subscribe(serverEventsChannel, aabb)
 	defer serverEventsHandle.Cancel()
 
-	sendMessage := func(kind string, content any) {
-		contentbytes, err := json.Marshal(content)
-		if err != nil {
-			log.Warn().Err(err).Msg("full chunk ser error")
-			return
-		}
-		conn.socketLock.Lock()
-		conn.socket.WriteJSON(message{
-			Kind:    kind,
-			Content: contentbytes,
-		})
-		conn.socketLock.Unlock()
-	}
+	sendMessage := conn.sendMessage
 
 	// send full chunk
 	sendTerrain := func() {
@@ -94,19 +118,7 @@ func (viewer *ViewerServer) handleClientSubscription(
 			return
 		}
 
-		tiles := make([]byte, 2*terrain.ChunkSize*terrain.ChunkSize)
-		for y := 0; y < terrain.ChunkSize; y++ {
-			for x := 0; x < terrain.ChunkSize; x++ {
-				i := 2 * (x + y*terrain.ChunkSize)
-				tile := chunk.GetTile(Point{X: x, Y: y})
-				tiles[i] = byte(tile.Kind)
-				tiles[i+1] = byte(tile.Value)
-			}
-		}
-		sendMessage("fullchunk", fullChunkContent{
-			ChunkPos: chunkPos,
-			Tiles:    tiles,
-		})
+		sendMessage("fullchunk", newFullChunkContent(chunk))
 	}
 
 	sendUnit := func(unit server.IUnit) bool {
@@ -117,13 +129,7 @@ func (viewer *ViewerServer) handleClientSubscription(
 		if conn.knownUnits[unit.GetId()] {
 			return false
 		}
-		sendMessage("unit", unitContent{
-			OpCode:   unit.GetOpCode(),
-			UnitId:   unit.GetId(),
-			Owner:    unit.GetOwner(),
-			Position: unit.GetPosition(),
-			Upgraded: unit.IsUpgraded(),
-		})
+		sendMessage("unit", newUnitContent(unit))
 		conn.knownUnits[unit.GetId()] = true
 		return false
 	}
@@ -136,25 +142,11 @@ func (viewer *ViewerServer) handleClientSubscription(
 		if conn.knownPlayers[player.GetId()] {
 			return false
 		}
-		sendMessage("playerSpawn", playerSpawnContent{
-			Id:            player.GetId(),
-			SpawnPosition: player.GetSpawnPoint(),
-			Username:      player.GetUsername(),
-			Resources:     player.GetResources(),
-		})
+		sendMessage("playerSpawn", newPlayerSpawnContent(player))
 		conn.knownPlayers[player.GetId()] = true
 		return false
 	}
 
-	getActionData := func(action *server.Action) actionData {
-		data := actionData{
-			ActionOpCode: action.OpCode,
-			ReportId:     action.ReportId,
-			Parameter:    action.Parameter,
-		}
-		return data
-	}
-
 	sendTerrain()
 
 	for _, entity := range viewer.Server.Entities().GetAllIntersects(aabb) {
@@ -228,6 +220,16 @@ func (viewer *ViewerServer) handleClientSubscription(
 					break
 				}
 			}
+			if e, ok := event.(*server.UnitUpgradedEvent); ok {
+				// the unit will be sent already upgraded
+				if sendUnit(e.Unit) {
+					break
+				}
+
+				sendMessage("unitUpgraded", unitUpgradedContent{
+					UnitId: e.Unit.GetId(),
+				})
+			}
 			if e, ok := event.(*server.UnitStartedActionEvent); ok {
 				// note: we do skip the action...
 				if sendUnit(e.Unit) {
@@ -236,7 +238,7 @@ func (viewer *ViewerServer) handleClientSubscription(
 
 				sendMessage("unitStartedAction", unitStartedActionContent{
 					UnitId: e.Unit.GetId(),
-					Action: getActionData(e.Action),
+					Action: newActionData(e.Action),
 				})
 			}
 			if e, ok := event.(*server.UnitFinishedActionEvent); ok {
@@ -248,7 +250,7 @@ func (viewer *ViewerServer) handleClientSubscription(
 
 				content := unitFinishedActionContent{
 					UnitId: e.Unit.GetId(),
-					Action: getActionData(e.Action),
+					Action: newActionData(e.Action),
 					Report: e.Report,
 				}
 
@@ -270,6 +272,11 @@ func (viewer *ViewerServer) handleClientSubscription(
 				delete(conn.knownPlayers, e.Player.GetId())
 				conn.playersLock.Unlock()
 			}
+
+		// the subscription would otherwise never end, the chunks are not
+		// unsubscribed when the connection closes
+		case <-conn.closed:
+			return
 		// makes sure at lease once every 30s we check if we are still subed to
 		// the chunk
 		case <-time.After(time.Second * 30):
@@ -277,6 +284,122 @@ func (viewer *ViewerServer) handleClientSubscription(
 	}
 }
 
+// handles the events that are not bound to a chunk (and so would be sent
+// multiple times by handleClientSubscription)
+func (viewer *ViewerServer) handleGlobalEvents(conn *connection) {
+	serverEventsChannel := make(chan server.IServerEvent, 2048)
+	serverEventsHandle := viewer.Server.Events().SubscribeGlobal(serverEventsChannel)
+	defer serverEventsHandle.Cancel()
+
+	// last resources of the players that changed since the last flush
+	pendingResources := make(map[uid.Uid]model.Resources)
+	// nil when nothing is pending
+	var flushResources <-chan time.Time
+
+	for {
+		var event server.IServerEvent
+		select {
+		case <-conn.closed:
+			return
+		case <-flushResources:
+			flushResources = nil
+			conn.sendPlayerResources(pendingResources)
+			clear(pendingResources)
+			continue
+		case event = <-serverEventsChannel:
+		}
+
+		if e, ok := event.(*entities.PlayerResourcesEvent); ok {
+			pendingResources[e.Player.GetId()] = e.Resources
+			if flushResources == nil {
+				interval := mathutils.Max(viewer.Server.Ticker().TickDuration(), minPlayerResourcesInterval)
+				flushResources = time.After(interval)
+			}
+		}
+
+		if e, ok := event.(*entities.PlayerMessageEvent); ok {
+			conn.sendMessage("playerMessage", playerMessageContent{
+				Sender:    e.Sender.GetId(),
+				Recipient: e.Recipient.GetId(),
+				Message:   e.Message,
+			})
+		}
+		if e, ok := event.(*entities.PlayerAchievementEvent); ok {
+			conn.sendMessage("playerAchievement", playerAchievementContent{
+				Id:          e.Player.GetId(),
+				Achievement: e.Achievement,
+			})
+		}
+		if e, ok := event.(*server.TickerStateEvent); ok {
+			conn.sendMessage("tickerState", tickerStateContent{
+				TicksPerSecond: e.State.TicksPerSecond,
+				Paused:         e.State.Paused,
+			})
+		}
+		if e, ok := event.(*match.StateEvent); ok {
+			conn.sendMessage("matchState", e.Status)
+		}
+		if _, ok := event.(*server.ServerStoppedEvent); ok {
+			// makes handleClient return, which ends the subscriptions
+			conn.socket.Close()
+			return
+		}
+	}
+}
+
+// sends the given resources of the players known by the connection, the
+// others will get theirs with their playerSpawn message
+func (conn *connection) sendPlayerResources(resources map[uid.Uid]model.Resources) {
+	conn.playersLock.RLock()
+	defer conn.playersLock.RUnlock()
+
+	for id, res := range resources {
+		if !conn.knownPlayers[id] {
+			continue
+		}
+		conn.sendMessage("playerResources", playerResourcesContent{
+			Id:        id,
+			Resources: res,
+		})
+	}
+}
+
+// applies a ticker control message
+func (viewer *ViewerServer) handleTickerControl(mess message) error {
+	ticker := viewer.Server.Ticker()
+
+	switch mess.Kind {
+	case "pause":
+		if viewer.Match != nil {
+			return viewer.Match.Pause()
+		}
+		ticker.Pause()
+	case "resume":
+		if viewer.Match != nil {
+			return viewer.Match.Resume()
+		}
+		ticker.Resume()
+	case "step":
+		var content stepRequestContent
+		err := json.Unmarshal(mess.Content, &content)
+		if err != nil {
+			return err
+		}
+		if viewer.Match != nil {
+			return viewer.Match.Step(content.Ticks)
+		}
+		ticker.Step(content.Ticks)
+	case "setTps":
+		var content setTpsRequestContent
+		err := json.Unmarshal(mess.Content, &content)
+		if err != nil {
+			return err
+		}
+		ticker.SetTicksPerSecond(content.TicksPerSecond)
+	}
+	return nil
+}
+
 func (viewer *ViewerServer) handleClient(conn *websocket.Conn) {
 	var err error = nil
 
@@ -284,13 +407,17 @@ func (viewer *ViewerServer) handleClient(conn *websocket.Conn) {
 	defer log.Debug().Any("addr", conn.RemoteAddr()).Msg("Websocket connection closed")
 
 	log.Debug().Any("addr", conn.RemoteAddr()).Msg("New websocket connection")
+	viewer.websocketConnections.Inc()
+	defer viewer.websocketConnections.Dec()
 
 	connection := connection{
 		socket:           conn,
 		subscribedChunks: make(map[Point]bool),
 		knownUnits:       make(map[uid.Uid]bool),
 		knownPlayers:     make(map[uid.Uid]bool),
+		closed:           make(chan struct{}),
 	}
+	defer close(connection.closed)
 
 	{
 		var initMessage message
@@ -298,7 +425,7 @@ func (viewer *ViewerServer) handleClient(conn *websocket.Conn) {
 		var messContent initContent
 		messContent.ChunkSize = terrain.ChunkSize
 		messContent.Costs = viewer.Server.GetCosts()
-		messContent.Setup = viewer.Server.GetSetup()
+		messContent.Setup = viewer.Server.GetCurrentSetup()
 
 		bytes, err := json.Marshal(messContent)
 		if err != nil {
@@ -317,6 +444,20 @@ func (viewer *ViewerServer) handleClient(conn *websocket.Conn) {
 		}
 	}
 
+	{
+		// the setup already has the tps but not wether it is paused
+		state := viewer.Server.Ticker().GetState()
+		connection.sendMessage("tickerState", tickerStateContent{
+			TicksPerSecond: state.TicksPerSecond,
+			Paused:         state.Paused,
+		})
+	}
+	if viewer.Match != nil {
+		connection.sendMessage("matchState", viewer.Match.GetStatus())
+	}
+
+	go viewer.handleGlobalEvents(&connection)
+
 	for {
 		var mess message
 		//t, reader, err := conn.NextReader()
@@ -365,6 +506,18 @@ func (viewer *ViewerServer) handleClient(conn *websocket.Conn) {
 				Msg("Unsubscribed to a chunk")
 
 			connection.chunksLock.Unlock()
+		case "pause", "resume", "step", "setTps":
+			if !viewer.AllowTickerControl {
+				log.Debug().
+					Any("addr", conn.RemoteAddr()).
+					Any("kind", mess.Kind).
+					Msg("Ticker control is not allowed")
+				break
+			}
+			err = viewer.handleTickerControl(mess)
+			if err != nil {
+				goto softerror
+			}
 		default:
 			log.Debug().
 				Any("addr", conn.RemoteAddr()).
@@ -397,6 +550,15 @@ error:
 }
 
 func (viewer *ViewerServer) Start() {
+	handler := viewer.Handler()
+
+	log.Info().Str("addr", viewer.Addr).Msg("Viewer server starting")
+	http.ListenAndServe(viewer.Addr, handler)
+}
+
+// returns the handler of the /websocket route, to be served on Addr by Start
+// or mounted by a game manager
+func (viewer *ViewerServer) Handler() http.Handler {
 	upgrader := websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
@@ -417,6 +579,5 @@ func (viewer *ViewerServer) Start() {
 		go viewer.handleClient(conn)
 	})
 
-	log.Info().Str("addr", viewer.Addr).Msg("Viewer server starting")
-	http.ListenAndServe(viewer.Addr, router)
+	return router
 }
@@ -10,6 +10,10 @@ export type Point = {
 export type Action = {
   actionOpCode: string,
   reportId: string,
+  // effective cast time in ticks
+  cast: number,
+  startedAtTick: number,
+  finishedAtTick: number,
   // hahahah...
   parameter?: any,
 }
@@ -20,6 +24,8 @@ export type CreepsReport = {
   login: string,
   unitPosition: Point,
   status: "SUCCESS" | "ERROR",
+  startedAtTick: number,
+  finishedAtTick: number,
 }
 
 export type MoveReport = CreepsReport & {
@@ -76,6 +82,20 @@ export type UnsubscribeMessage = {
   }
 }
 
+// ticker controls are ignored unless the server allows them
+export type TickerControlMessage =
+  | { kind: "pause", content: {} }
+  | { kind: "resume", content: {} }
+  | { kind: "step", content: { ticks: number } }
+  | { kind: "setTps", content: { ticksPerSecond: number } };
+
+// only handled when the server plays a replay
+export type ReplayControlMessage =
+  | { kind: "replayPlay", content: {} }
+  | { kind: "replayPause", content: {} }
+  | { kind: "replaySeek", content: { tick: number } }
+  | { kind: "replaySpeed", content: { speed: number } };
+
 export type FullchunkMessage = {
   kind: "fullchunk",
   content: {
@@ -111,6 +131,13 @@ export type UnitDespawnedMessage = {
   }
 }
 
+export type UnitUpgradedMessage = {
+  kind: "unitUpgraded",
+  content: {
+    unitId: string,
+  }
+}
+
 export type UnitStartedActionMessage = {
   kind: "unitStartedAction",
   content: {
@@ -134,8 +161,7 @@ export type PlayerSpawnMessage = {
   	id: string,
   	spawnPosition: Point,
   	username: string,
-    // TODO: Describe
-  	resources: unknown,
+  	resources: Resources,
   }
 }
 
@@ -146,17 +172,140 @@ export type PlayerDespawnMessage = {
   }
 }
 
+export type PlayerMessageMessage = {
+  kind: "playerMessage",
+  content: {
+    sender: string,
+    recipient: string,
+    message: string,
+  }
+}
+
+export type PlayerAchievementMessage = {
+  kind: "playerAchievement",
+  content: {
+    id: string,
+    achievement: string,
+  }
+}
+
+// sent at most once per tick for each player whose resources changed
+export type PlayerResourcesMessage = {
+  kind: "playerResources",
+  content: {
+    id: string,
+    resources: Resources,
+  }
+}
+
+export type TickerStateMessage = {
+  kind: "tickerState",
+  content: {
+    // 0 means as fast as possible
+    ticksPerSecond: number,
+    paused: boolean,
+  }
+}
+
+export type MatchRanking = {
+  rank: number,
+  username: string,
+  score: number,
+  alive: boolean,
+  diedAtTick: number | null,
+  units: number,
+  buildings: number,
+  resources: Resources,
+  achievements: string[],
+}
+
+// only sent if the game is played as a match
+export type MatchStateMessage = {
+  kind: "matchState",
+  content: {
+    state: "waiting" | "countdown" | "running" | "finished",
+    minPlayers: number,
+    // date, only while counting down
+    startsAt: string | null,
+    startedAtTick: number | null,
+    // 0 if there is no limit
+    tickLimit: number,
+    results: {
+      serverId: string,
+      reason: "lastPlayerAlive" | "tickLimit" | "scoreThreshold" | "stopped",
+      startedAtTick: number,
+      finishedAtTick: number,
+      finishedAt: string,
+      winner: string | null,
+      rankings: MatchRanking[],
+    } | null,
+  }
+}
+
+// only sent by replays
+export type RaidStartedMessage = {
+  kind: "raidStarted",
+  content: {
+    id: string,
+    owner: string,
+    campPosition: Point,
+    targetPosition: Point,
+  }
+}
+
+// only sent by replays
+export type CommandMessage = {
+  kind: "command",
+  content: {
+    response: {
+      opcode: string,
+      reportId: string | null,
+      errorCode: string | null,
+      error: string | null,
+      login: string,
+      unitId: string | null,
+      misses: number,
+    },
+    parameter?: any,
+  }
+}
+
+// only sent by replays
+export type ReplayStateMessage = {
+  kind: "replayState",
+  content: {
+    tick: number,
+    firstTick: number,
+    lastTick: number,
+    paused: boolean,
+    speed: number,
+  }
+}
+
 export type RecvMessage =
   | InitMessage
   | FullchunkMessage
   | TileChangeMessage
   | UnitMessage
   | UnitDespawnedMessage
+  | UnitUpgradedMessage
   | UnitStartedActionMessage
   | UnitFinishedActionMessage
   | PlayerSpawnMessage
-  | PlayerDespawnMessage;
-export type SendMessage = SubscribeMessage | UnsubscribeMessage;
+  | PlayerDespawnMessage
+  | PlayerMessageMessage
+  | PlayerAchievementMessage
+  | PlayerResourcesMessage
+  | TickerStateMessage
+  | MatchStateMessage
+  | RaidStartedMessage
+  | CommandMessage
+  | ReplayStateMessage;
+export type SendMessage =
+  | SubscribeMessage
+  | UnsubscribeMessage
+  | TickerControlMessage
+  | ReplayControlMessage;
 
 export class MessageEvent extends Event {
   public readonly message: RecvMessage;
@@ -193,6 +342,7 @@ export class Api {
     return this.#isConnected;
   }
   #initMessage: InitMessage | null = null;
+  #tickerState: TickerStateMessage | null = null;
   get initMessage(): InitMessage | null {
     return this.#initMessage;
   }
@@ -255,6 +405,8 @@ export class Api {
         }
         if (c.kind == "init")
           this.#initMessage = c;
+        if (c.kind == "tickerState")
+          this.#tickerState = c;
         this.#events.dispatchEvent(new MessageEvent(c));
       }
       catch (e) {
@@ -306,9 +458,14 @@ export class Api {
   }
 
   secondsPerTicks(): number {
-    if (!this.#initMessage)
+    const tps = this.#tickerState?.content.ticksPerSecond
+      ?? this.#initMessage?.content.setup.ticksPerSecond;
+    if (tps == undefined)
       return 1;
-    return 1 / this.#initMessage.content.setup.ticksPerSecond;
+    // as fast as possible, actions are pretty much instant
+    if (tps <= 0)
+      return Number.MIN_VALUE;
+    return 1 / tps;
   }
 
   public addEventListener(
@@ -1,12 +1,14 @@
 import { vec } from "~/src/utils/geom"
 import { IRenderer, Renderer } from "./worldRenderer";
-import { Api, PlayerSpawnMessage } from "./api";
+import { Api, MatchStateMessage, PlayerSpawnMessage, Resources } from "./api";
 
 export class OverlayRenderer implements IRenderer {
   private readonly renderer: Renderer;
 
   private eventAbort = new AbortController();
   private players = new Map<string, PlayerSpawnMessage>();
+  // null unless the game is played as a match
+  private matchState: MatchStateMessage | null = null;
 
   private renderPlayerUsernames = true;
 
@@ -40,6 +42,26 @@ export class OverlayRenderer implements IRenderer {
     }, {
       signal: this.eventAbort.signal,
     });
+
+    api.addEventListener("message", event => {
+      const message = event.message;
+      if (message.kind != "playerResources")
+        return;
+      const player = this.players.get(message.content.id);
+      if (player)
+        player.content.resources = message.content.resources;
+    }, {
+      signal: this.eventAbort.signal,
+    });
+
+    api.addEventListener("message", event => {
+      const message = event.message;
+      if (message.kind != "matchState")
+        return;
+      this.matchState = message;
+    }, {
+      signal: this.eventAbort.signal,
+    });
   }
 
   private update(_dt: number) {
@@ -51,6 +73,8 @@ export class OverlayRenderer implements IRenderer {
 
     const ctx = this.renderer.ctx;
 
+    this.renderMatchState();
+
     if (!this.renderPlayerUsernames) {
       return;
     }
@@ -67,6 +91,67 @@ export class OverlayRenderer implements IRenderer {
       ctx.font = `${18 / this.renderer.cameraScale}px arial`;
       ctx.fillStyle = "rgba(255, 255, 255, 0.75)";
       ctx.fillText(player.content.username, sp.x, sp.y);
+
+      const resources = formatResources(player.content.resources);
+      const rp = sp.plus(0, 0.8);
+      ctx.font = `${12 / this.renderer.cameraScale}px arial`;
+      ctx.strokeText(resources, rp.x, rp.y);
+      ctx.fillText(resources, rp.x, rp.y);
+    }
+  }
+
+  // drawn in screen coordinates at the top of the canvas
+  private renderMatchState() {
+    if (this.matchState == null)
+      return;
+    const match = this.matchState.content;
+    const ctx = this.renderer.ctx;
+
+    let text = "";
+    switch (match.state) {
+      case "waiting":
+        text = `Match waiting for ${match.minPlayers} players`;
+        break;
+      case "countdown": {
+        const startsIn = match.startsAt == null ? 0
+          : Math.max(0, Math.ceil((Date.parse(match.startsAt) - Date.now()) / 1000));
+        text = `Match starts in ${startsIn}s`;
+        break;
+      }
+      case "running":
+        text = `Match running since tick ${match.startedAtTick}`;
+        if (match.tickLimit > 0)
+          text += ` (ends at tick ${(match.startedAtTick ?? 0) + match.tickLimit})`;
+        break;
+      case "finished": {
+        const winner = match.results?.winner;
+        text = winner == null ? "Match finished" : `Match finished, ${winner} won`;
+        break;
+      }
     }
+
+    ctx.save();
+    ctx.resetTransform();
+    ctx.textAlign = "center";
+    ctx.textBaseline = "top";
+    ctx.font = "20px arial";
+    ctx.lineWidth = 3;
+    ctx.strokeStyle = "rgba(0, 0, 0, 0.75)";
+    ctx.strokeText(text, this.renderer.canvas.width / 2, 10);
+    ctx.fillStyle = "rgba(255, 255, 255, 0.9)";
+    ctx.fillText(text, this.renderer.canvas.width / 2, 10);
+    ctx.restore();
   }
 }
+
+function formatResources(res: Resources): string {
+  return [
+    `rock ${res.rock}`,
+    `wood ${res.wood}`,
+    `food ${res.food}`,
+    `oil ${res.oil}`,
+    `copper ${res.copper}`,
+    `plank ${res.woodPlank}`,
+  ].join(" · ");
+}
+}
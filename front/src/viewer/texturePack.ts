@@ -61,6 +61,10 @@ export class TexturePack extends EventTarget {
     null,
     // Road
     "/road.png",
+    // Unknown
+    null,
+    // WorldBorder
+    "/kenney_micro_roguelike/water.png",
   ];
   private unitsUrlTable: {[key: string]: string|string[]|undefined} = {
     "citizen": [
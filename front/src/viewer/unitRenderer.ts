@@ -38,6 +38,12 @@ export class UnitRenderer implements IRenderer {
           this.lastUnitMessage.delete(event.message.content.unitId);
           break;
         }
+        case "unitUpgraded": {
+          const unit = this.lastUnitMessage.get(event.message.content.unitId);
+          if (unit)
+            unit.content.upgraded = true;
+          break;
+        }
         case "unitStartedAction": {
           this.unitsActions.set(event.message.content.unitId, {
             action: event.message.content.action,
@@ -94,7 +100,7 @@ export class UnitRenderer implements IRenderer {
     const cost = action == null ? null : this.api.getActionCost(action.action.actionOpCode);
     
     if (action != null && cost != null && action.state == "running") {
-      let prop = action.elapsed / (cost.cast * this.api.secondsPerTicks());
+      let prop = action.elapsed / ((action.action.cast ?? cost.cast) * this.api.secondsPerTicks());
       prop = Math.min(Math.max(prop, 0), 1);
       // to add cases here make sure they are handled in api.getActionCost too
       switch (action.action.actionOpCode) {